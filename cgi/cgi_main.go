@@ -5,14 +5,18 @@
 package cgi
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"maps"
 	"net/http"
 	"os"
+	"os/signal"
 	"path"
 	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -21,6 +25,9 @@ func cgiMain() {
 	case "/bar", "/test.cgi", "/myscript/bar", "/test.cgi/extrapath":
 		testCGI()
 		return
+	case "/nph.cgi":
+		nphCGI()
+		return
 	}
 	childCGIProcess()
 }
@@ -77,6 +84,20 @@ func testCGI() {
 	fmt.Printf("cwd=%s\r\n", cwd)
 }
 
+// nphCGI is a non-parsed header CGI program, writing a complete HTTP
+// response that echoes the request body.
+func nphCGI() {
+	n, _ := strconv.Atoi(os.Getenv("CONTENT_LENGTH"))
+	body := make([]byte, n)
+	io.ReadFull(os.Stdin, body)
+	fmt.Printf("%s 103 Early Hints\r\nLink: </style.css>; rel=preload\r\n\r\n", os.Getenv("SERVER_PROTOCOL"))
+	fmt.Printf("%s 201 Created\r\n", os.Getenv("SERVER_PROTOCOL"))
+	fmt.Printf("Content-Type: text/plain\r\nX-NPH: yes\r\nConnection: close, X-Hop\r\nX-Hop: 1\r\n")
+	fmt.Printf("Transfer-Encoding: chunked\r\nTrailer: X-Sum\r\n\r\n")
+	msg := "got: " + string(body)
+	fmt.Printf("%x\r\n%s\r\n0\r\nX-Sum: %d\r\n\r\n", len(msg), msg, len(body))
+}
+
 type neverEnding byte
 
 func (b neverEnding) Read(p []byte) (n int, err error) {
@@ -109,13 +130,52 @@ func childCGIProcess() {
 		}
 		rw.Header().Set("X-Test-Header", "X-Test-Value")
 		req.ParseForm()
+		if d := req.FormValue("sleep"); d != "" {
+			if req.FormValue("ignore-sigterm") == "1" {
+				signal.Ignore(syscall.SIGTERM)
+			}
+			dur, _ := time.ParseDuration(d)
+			select {
+			case <-time.After(dur):
+			case <-req.Context().Done():
+				os.Exit(1)
+			}
+		}
+		if req.FormValue("wait-context") == "1" {
+			<-req.Context().Done()
+			fmt.Fprintf(os.Stderr, "context: %v\n", context.Cause(req.Context()))
+			return
+		}
 		if req.FormValue("no-body") == "1" {
 			return
 		}
+		if req.FormValue("flush") == "1" {
+			io.WriteString(rw, "first\n")
+			http.NewResponseController(rw).Flush()
+			time.Sleep(500 * time.Millisecond)
+			io.WriteString(rw, "second\n")
+			return
+		}
+		if req.FormValue("trailer") == "1" {
+			rw.Header().Set("Trailer", "X-Sum")
+			n, _ := io.WriteString(rw, "trailer body")
+			rw.Header().Set("X-Sum", strconv.Itoa(n))
+			rw.Header().Set(http.TrailerPrefix+"X-Late", "late")
+			return
+		}
 		if eb, ok := req.Form["exact-body"]; ok {
 			io.WriteString(rw, eb[0])
 			return
 		}
+		if req.FormValue("proc-limits") == "1" {
+			b, _ := os.ReadFile("/proc/self/limits")
+			rw.Write(b)
+			return
+		}
+		if req.FormValue("ids") == "1" {
+			fmt.Fprintf(rw, "uid=%d\ngid=%d\n", os.Getuid(), os.Getgid())
+			return
+		}
 		if req.FormValue("write-forever") == "1" {
 			io.Copy(rw, neverEnding('a'))
 			for {
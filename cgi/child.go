@@ -9,16 +9,21 @@ package cgi
 
 import (
 	"bufio"
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/johnsiilver/http/httputil"
 )
 
 // Request returns the HTTP request as represented in the current
@@ -138,10 +143,35 @@ func RequestFromMap(params map[string]string) (*http.Request, error) {
 	return r, nil
 }
 
+// ErrTerminated is the cause of the cancellation of the context of a
+// request served by [Serve] when the process is asked to exit, as
+// when a [Handler] stops a child that exceeded its Timeout.
+var ErrTerminated = errors.New("cgi: process terminated")
+
 // Serve executes the provided [Handler] on the currently active CGI
 // request, if any. If there's no current CGI environment
 // an error is returned. The provided handler may be nil to use
 // [http.DefaultServeMux].
+//
+// The request's context is canceled when the handler returns, when
+// writing the response fails, as when the web server has stopped
+// reading it, and on Unix when the process receives SIGTERM, with
+// cause [ErrTerminated]. SIGTERM then no longer ends the process
+// while the handler runs, so long-running handlers should return
+// when the context is done.
+//
+// The response writer supports [http.ResponseController]. Flushing
+// sends the header and any buffered body to the web server; whether
+// it reaches the client promptly depends on the server. Deadlines are
+// supported only if standard input and output support them, which
+// they usually do not.
+//
+// Trailers are sent if the handler declares them in the Trailer
+// header before writing the response header, as for an HTTP/1 server.
+// CGI has no means of sending trailers, so the body is then sent
+// using chunked encoding, followed by the trailer fields, as for
+// HTTP/1.1. This convention is understood by this package's
+// [Handler], but not necessarily by other web servers.
 func Serve(handler http.Handler) error {
 	req, err := Request()
 	if err != nil {
@@ -153,17 +183,34 @@ func Serve(handler http.Handler) error {
 	if handler == nil {
 		handler = http.DefaultServeMux
 	}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(context.Canceled)
+	stop := notifyTerminate(cancel)
+	defer stop()
+	req = req.WithContext(ctx)
 	rw := &response{
 		req:    req,
 		header: make(http.Header),
-		bufw:   bufio.NewWriter(os.Stdout),
+		bufw:   bufio.NewWriter(&cancelWriter{os.Stdout, cancel}),
+		in:     os.Stdin,
+		out:    os.Stdout,
 	}
 	handler.ServeHTTP(rw, req)
-	rw.Write(nil) // make sure a response is sent
-	if err = rw.bufw.Flush(); err != nil {
-		return err
+	return rw.finish()
+}
+
+// A cancelWriter cancels a context when a write fails.
+type cancelWriter struct {
+	w      io.Writer
+	cancel context.CancelCauseFunc
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if err != nil {
+		w.cancel(fmt.Errorf("cgi: writing response: %w", err))
 	}
-	return nil
+	return n, err
 }
 
 type response struct {
@@ -173,10 +220,54 @@ type response struct {
 	wroteHeader    bool
 	wroteCGIHeader bool
 	bufw           *bufio.Writer
+
+	in, out  *os.File       // standard input and output, for deadlines; nil in tests
+	chunked  io.WriteCloser // body writer when sending trailers, or nil
+	trailers []string       // trailers declared before the header was written
 }
 
 func (r *response) Flush() {
-	r.bufw.Flush()
+	r.FlushError()
+}
+
+// FlushError writes the header, if it has not been written, and any
+// buffered data to the web server.
+func (r *response) FlushError() error {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.writeCGIHeader(nil)
+	return r.bufw.Flush()
+}
+
+// SetReadDeadline sets the deadline for reading the request body. It
+// returns an error wrapping [http.ErrNotSupported] if standard input
+// does not support deadlines.
+func (r *response) SetReadDeadline(t time.Time) error {
+	return setDeadline(r.in, "read", (*os.File).SetReadDeadline, t)
+}
+
+// SetWriteDeadline sets the deadline for writing the response. It
+// returns an error wrapping [http.ErrNotSupported] if standard output
+// does not support deadlines.
+func (r *response) SetWriteDeadline(t time.Time) error {
+	return setDeadline(r.out, "write", (*os.File).SetWriteDeadline, t)
+}
+
+func setDeadline(f *os.File, kind string, set func(*os.File, time.Time) error, t time.Time) error {
+	if f == nil {
+		return fmt.Errorf("cgi: %s deadlines: %w", kind, http.ErrNotSupported)
+	}
+	if err := set(f, t); err != nil {
+		return fmt.Errorf("cgi: %s deadlines on %s: %w (%v)", kind, f.Name(), http.ErrNotSupported, err)
+	}
+	return nil
+}
+
+// EnableFullDuplex does nothing: the request body may always be read
+// while the response is written.
+func (r *response) EnableFullDuplex() error {
+	return nil
 }
 
 func (r *response) Header() http.Header {
@@ -190,6 +281,13 @@ func (r *response) Write(p []byte) (n int, err error) {
 	if !r.wroteCGIHeader {
 		r.writeCGIHeader(p)
 	}
+	if r.chunked != nil {
+		if len(p) == 0 {
+			// A zero-length chunk would end the body.
+			return 0, nil
+		}
+		return r.chunked.Write(p)
+	}
 	return r.bufw.Write(p)
 }
 
@@ -216,7 +314,49 @@ func (r *response) writeCGIHeader(p []byte) {
 	if _, hasType := r.header["Content-Type"]; !hasType {
 		r.header.Set("Content-Type", http.DetectContentType(p))
 	}
-	r.header.Write(r.bufw)
+	for _, v := range r.header["Trailer"] {
+		for k := range strings.SplitSeq(v, ",") {
+			if k = textproto.TrimString(k); k != "" {
+				r.trailers = append(r.trailers, http.CanonicalHeaderKey(k))
+			}
+		}
+	}
+	exclude := make(map[string]bool)
+	for k := range r.header {
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			exclude[k] = true
+		}
+	}
+	if len(r.trailers) > 0 {
+		r.header.Set("Transfer-Encoding", "chunked")
+		exclude["Content-Length"] = true
+		r.chunked = httputil.NewChunkedWriter(r.bufw)
+	}
+	r.header.WriteSubset(r.bufw, exclude)
 	r.bufw.WriteString("\r\n")
 	r.bufw.Flush()
 }
+
+// finish completes the response after the handler has returned.
+func (r *response) finish() error {
+	r.Write(nil) // make sure a response is sent
+	if r.chunked != nil {
+		if err := r.chunked.Close(); err != nil {
+			return err
+		}
+		trailer := make(http.Header)
+		for _, k := range r.trailers {
+			if vv, ok := r.header[k]; ok {
+				trailer[k] = vv
+			}
+		}
+		for k, vv := range r.header {
+			if k, ok := strings.CutPrefix(k, http.TrailerPrefix); ok {
+				trailer[http.CanonicalHeaderKey(k)] = vv
+			}
+		}
+		trailer.Write(r.bufw)
+		r.bufw.WriteString("\r\n")
+	}
+	return r.bufw.Flush()
+}
@@ -0,0 +1,15 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !unix
+
+package cgi
+
+import "context"
+
+// notifyTerminate does nothing: there is no portable signal asking a
+// process to exit.
+func notifyTerminate(context.CancelCauseFunc) (stop func()) {
+	return func() {}
+}
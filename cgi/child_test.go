@@ -9,9 +9,14 @@ package cgi
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
+	"io"
 	"net/http"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/johnsiilver/http/httptest"
 )
@@ -207,3 +212,80 @@ func TestResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestResponseFlush(t *testing.T) {
+	var buf bytes.Buffer
+	resp := &response{
+		req:    httptest.NewRequest("GET", "/", nil),
+		header: http.Header{},
+		bufw:   bufio.NewWriter(&buf),
+	}
+	if err := http.NewResponseController(resp).Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got := buf.String(); !strings.HasPrefix(got, "Status: 200 OK\r\n") || !strings.HasSuffix(got, "\r\n\r\n") {
+		t.Errorf("after Flush, output = %q; want the CGI header", got)
+	}
+}
+
+func TestResponseDeadlines(t *testing.T) {
+	resp := &response{
+		req:    httptest.NewRequest("GET", "/", nil),
+		header: http.Header{},
+		bufw:   bufio.NewWriter(io.Discard),
+	}
+	rc := http.NewResponseController(resp)
+	if err := rc.SetWriteDeadline(time.Now()); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("SetWriteDeadline = %v; want ErrNotSupported", err)
+	}
+	if err := rc.SetReadDeadline(time.Now()); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("SetReadDeadline = %v; want ErrNotSupported", err)
+	}
+}
+
+func TestResponseTrailers(t *testing.T) {
+	var buf bytes.Buffer
+	resp := &response{
+		req:    httptest.NewRequest("GET", "/", nil),
+		header: http.Header{},
+		bufw:   bufio.NewWriter(&buf),
+	}
+	resp.Header().Set("Trailer", "X-Sum")
+	resp.Header().Set("Content-Type", "text/plain")
+	io.WriteString(resp, "hello")
+	resp.Header().Set("X-Sum", "5")
+	resp.Header().Set(http.TrailerPrefix+"X-Late", "late")
+	if err := resp.finish(); err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	want := "Status: 200 OK\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"Trailer: X-Sum\r\n" +
+		"Transfer-Encoding: chunked\r\n" +
+		"\r\n" +
+		"5\r\nhello\r\n0\r\n" +
+		"X-Late: late\r\n" +
+		"X-Sum: 5\r\n" +
+		"\r\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestCancelWriter(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	defer w.Close()
+	cw := &cancelWriter{w, cancel}
+	if _, err := cw.Write([]byte("x")); err == nil {
+		t.Fatal("Write to closed pipe succeeded")
+	}
+	if ctx.Err() == nil {
+		t.Errorf("context not canceled after failed write")
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package cgi
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyTerminate arranges for cancel to be called with ErrTerminated
+// when the process receives SIGTERM, until stop is called. It also
+// makes writes to a closed standard output return EPIPE rather than
+// killing the process, so that the failure cancels the request.
+func notifyTerminate(cancel context.CancelCauseFunc) (stop func()) {
+	term := make(chan os.Signal, 1)
+	pipe := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(term, syscall.SIGTERM)
+	signal.Notify(pipe, syscall.SIGPIPE)
+	go func() {
+		select {
+		case <-term:
+			cancel(ErrTerminated)
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(term)
+		signal.Stop(pipe)
+		close(done)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Running a directory of CGI scripts.
+
+package cgi
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultStatCacheTTL is the default value of [DirHandler.StatCacheTTL].
+const DefaultStatCacheTTL = 5 * time.Second
+
+// maxStatCacheEntries bounds the stat cache of a DirHandler, which
+// also holds the results for paths that do not exist.
+const maxStatCacheEntries = 1024
+
+// A DirHandler runs the CGI scripts in a directory, like Apache's
+// ScriptAlias. The request path below Root names a script in
+// ScriptDir, possibly in a subdirectory, and the rest of the path is
+// passed to the script as PATH_INFO.
+//
+// For example, with Root "/cgi-bin/", a request for
+// /cgi-bin/tools/report.py/2024/q1 runs the script tools/report.py in
+// ScriptDir, with SCRIPT_NAME "/cgi-bin/tools/report.py" and
+// PATH_INFO "/2024/q1".
+//
+// Requests whose path has a segment that is empty, "." or not a
+// local file name, such as "..", are rejected with 404 (Not Found).
+// Requests naming a directory, a file that is neither executable nor
+// run by one of the Interpreters, or a symbolic link leading out of
+// ScriptDir are rejected with 403 (Forbidden).
+type DirHandler struct {
+	// Handler configures the child processes. Its Root is the URI
+	// prefix of the scripts and its Path is ignored. If its Dir is
+	// empty, each script runs in its own directory. Limits such as
+	// MaxConcurrent apply to all the scripts together.
+	Handler
+
+	ScriptDir string // directory holding the scripts
+
+	// Interpreters maps file name extensions, such as ".py", to the
+	// program running scripts with that extension, such as
+	// "python3". A program name without a path separator is looked
+	// up in PATH. The script file is passed to the program as its
+	// first argument, and need not be executable.
+	Interpreters map[string]string
+
+	// StatCacheTTL is how long the result of looking up a file in
+	// ScriptDir is cached. If zero, DefaultStatCacheTTL is used; if
+	// negative, results are not cached.
+	StatCacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]statEntry // by slash-separated path in ScriptDir
+}
+
+// fileKind classifies a file in a DirHandler's ScriptDir.
+type fileKind int
+
+const (
+	fileMissing fileKind = iota
+	fileDir
+	fileScript
+	fileForbidden
+)
+
+type statEntry struct {
+	kind    fileKind
+	interp  string // interpreter running a fileScript, or ""
+	expires time.Time
+}
+
+func (d *DirHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	root := strings.TrimRight(d.Root, "/")
+	rest, ok := strings.CutPrefix(req.URL.Path, root)
+	if !ok || !strings.HasPrefix(rest, "/") {
+		http.NotFound(rw, req)
+		return
+	}
+	dir, err := filepath.Abs(d.ScriptDir)
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		d.printf("cgi: %v", err)
+		return
+	}
+	segs := strings.Split(rest[1:], "/")
+	for i, seg := range segs {
+		if seg == "." || !filepath.IsLocal(seg) {
+			http.NotFound(rw, req)
+			return
+		}
+		rel := strings.Join(segs[:i+1], "/")
+		e, err := d.lookup(rel)
+		if err != nil {
+			rw.WriteHeader(http.StatusInternalServerError)
+			d.printf("cgi: %v", err)
+			return
+		}
+		switch e.kind {
+		case fileDir:
+			continue
+		case fileScript:
+			d.serve(rw, req, script{
+				path:   filepath.Join(dir, filepath.FromSlash(rel)),
+				root:   root + "/" + rel,
+				interp: e.interp,
+			})
+		case fileForbidden:
+			http.Error(rw, "403 Forbidden", http.StatusForbidden)
+		default:
+			http.NotFound(rw, req)
+		}
+		return
+	}
+	// The path names a directory.
+	http.Error(rw, "403 Forbidden", http.StatusForbidden)
+}
+
+// lookup returns the kind of the file at the slash-separated path rel
+// in d.ScriptDir, from the cache if possible.
+func (d *DirHandler) lookup(rel string) (statEntry, error) {
+	ttl := d.StatCacheTTL
+	if ttl == 0 {
+		ttl = DefaultStatCacheTTL
+	}
+	now := time.Now()
+	if ttl > 0 {
+		d.mu.Lock()
+		e, ok := d.cache[rel]
+		d.mu.Unlock()
+		if ok && now.Before(e.expires) {
+			return e, nil
+		}
+	}
+	e, err := d.stat(rel)
+	if err != nil || ttl < 0 {
+		return e, err
+	}
+	e.expires = now.Add(ttl)
+	d.mu.Lock()
+	if d.cache == nil || len(d.cache) >= maxStatCacheEntries {
+		d.cache = make(map[string]statEntry)
+	}
+	d.cache[rel] = e
+	d.mu.Unlock()
+	return e, nil
+}
+
+// stat returns the kind of the file at the slash-separated path rel in
+// d.ScriptDir. Symbolic links are followed only within ScriptDir.
+func (d *DirHandler) stat(rel string) (statEntry, error) {
+	root, err := os.OpenRoot(d.ScriptDir)
+	if err != nil {
+		return statEntry{}, err
+	}
+	defer root.Close()
+	fi, err := root.Stat(filepath.FromSlash(rel))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return statEntry{kind: fileMissing}, nil
+	case err != nil:
+		// Permission denied, or a link out of ScriptDir.
+		return statEntry{kind: fileForbidden}, nil
+	case fi.IsDir():
+		return statEntry{kind: fileDir}, nil
+	case !fi.Mode().IsRegular():
+		return statEntry{kind: fileForbidden}, nil
+	}
+	if prog, ok := d.Interpreters[filepath.Ext(rel)]; ok {
+		interp, err := exec.LookPath(prog)
+		if err != nil {
+			return statEntry{}, fmt.Errorf("interpreter for %s: %w", rel, err)
+		}
+		return statEntry{kind: fileScript, interp: interp}, nil
+	}
+	if !executable(fi) {
+		return statEntry{kind: fileForbidden}, nil
+	}
+	return statEntry{kind: fileScript}, nil
+}
+
+// executable reports whether fi describes a file the system can run.
+func executable(fi fs.FileInfo) bool {
+	if runtime.GOOS == "windows" {
+		switch strings.ToLower(filepath.Ext(fi.Name())) {
+		case ".exe", ".com", ".bat", ".cmd":
+			return true
+		}
+		return false
+	}
+	return fi.Mode().Perm()&0o111 != 0
+}
@@ -0,0 +1,143 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgi
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/johnsiilver/http/httptest"
+	"github.com/johnsiilver/http/internal/testenv"
+)
+
+// copyExecutable copies the test binary, which is also the CGI
+// program run by the tests, to path.
+func copyExecutable(t *testing.T, path string) {
+	t.Helper()
+	src, err := os.Open(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newScriptDir returns a DirHandler for a new directory of scripts.
+func newScriptDir(t *testing.T) *DirHandler {
+	testenv.MustHaveExec(t)
+	if runtime.GOOS == "windows" {
+		t.Skip("test scripts are not executable on windows")
+	}
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	copyExecutable(t, filepath.Join(dir, "sub", "test.cgi"))
+	interp := filepath.Join(t.TempDir(), "interp")
+	copyExecutable(t, interp)
+	for _, name := range []string{"hello.py", "data.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/false\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &DirHandler{
+		Handler:      Handler{Root: "/cgi-bin/"},
+		ScriptDir:    dir,
+		Interpreters: map[string]string{".py": interp},
+	}
+}
+
+func TestDirHandler(t *testing.T) {
+	d := newScriptDir(t)
+	if err := os.Symlink(os.Args[0], filepath.Join(d.ScriptDir, "escape.cgi")); err != nil {
+		t.Logf("cannot create symlink: %v", err)
+	}
+
+	for _, tt := range []struct {
+		path     string
+		wantCode int
+		want     map[string]string
+	}{
+		{
+			path:     "/cgi-bin/sub/test.cgi/extra/info",
+			wantCode: http.StatusOK,
+			want: map[string]string{
+				"env-SCRIPT_NAME":     "/cgi-bin/sub/test.cgi",
+				"env-PATH_INFO":       "/extra/info",
+				"env-SCRIPT_FILENAME": filepath.Join(d.ScriptDir, "sub", "test.cgi"),
+			},
+		},
+		{
+			path:     "/cgi-bin/sub/test.cgi",
+			wantCode: http.StatusOK,
+			want: map[string]string{
+				"env-SCRIPT_NAME": "/cgi-bin/sub/test.cgi",
+				"env-PATH_INFO":   "",
+			},
+		},
+		{
+			path:     "/cgi-bin/hello.py/x",
+			wantCode: http.StatusOK,
+			want: map[string]string{
+				"env-SCRIPT_NAME":     "/cgi-bin/hello.py",
+				"env-PATH_INFO":       "/x",
+				"env-SCRIPT_FILENAME": filepath.Join(d.ScriptDir, "hello.py"),
+			},
+		},
+		{path: "/cgi-bin/data.txt", wantCode: http.StatusForbidden},
+		{path: "/cgi-bin/sub", wantCode: http.StatusForbidden},
+		{path: "/cgi-bin/sub/", wantCode: http.StatusNotFound},
+		{path: "/cgi-bin/missing.cgi", wantCode: http.StatusNotFound},
+		{path: "/cgi-bin/sub/../sub/test.cgi", wantCode: http.StatusNotFound},
+		{path: "/cgi-bin/./sub/test.cgi", wantCode: http.StatusNotFound},
+		{path: "/cgi-bin//sub/test.cgi", wantCode: http.StatusNotFound},
+		{path: "/cgi-binary/sub/test.cgi", wantCode: http.StatusNotFound},
+		{path: "/cgi-bin/escape.cgi", wantCode: http.StatusForbidden},
+	} {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "http://example.com/", nil)
+			req.URL.Path = tt.path
+			d.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d; want %d; body:\n%s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.want != nil {
+				runResponseChecks(t, rec, tt.want)
+			}
+		})
+	}
+}
+
+func TestDirHandlerStatCache(t *testing.T) {
+	d := newScriptDir(t)
+	name := filepath.Join(d.ScriptDir, "data.txt")
+	if e, err := d.lookup("data.txt"); err != nil || e.kind != fileForbidden {
+		t.Fatalf("lookup(data.txt) = %v, %v; want fileForbidden", e.kind, err)
+	}
+	if err := os.Chmod(name, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := d.lookup("data.txt"); e.kind != fileForbidden {
+		t.Errorf("after chmod, cached lookup(data.txt) = %v; want fileForbidden", e.kind)
+	}
+	d.StatCacheTTL = -1
+	if e, _ := d.lookup("data.txt"); e.kind != fileScript {
+		t.Errorf("after chmod, uncached lookup(data.txt) = %v; want fileScript", e.kind)
+	}
+}
@@ -16,7 +16,9 @@ package cgi
 
 import (
 	"bufio"
-	"fmt"
+	"bytes"
+	"context"
+	"errors"
 	"io"
 	"log"
 	"net"
@@ -25,16 +27,17 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/johnsiilver/http/httputil"
+	"github.com/johnsiilver/http/internal/cgienv"
 	"golang.org/x/net/http/httpguts"
 )
 
-var trailingPort = regexp.MustCompile(`:([0-9]+)$`)
-
 var osDefaultInheritEnv = func() []string {
 	switch runtime.GOOS {
 	case "darwin", "ios":
@@ -79,8 +82,73 @@ type Handler struct {
 	// If nil, a CGI response with a local URI path is instead sent
 	// back to the client and not redirected internally.
 	PathLocationHandler http.Handler
+
+	// NPH specifies that the executable is a non-parsed header
+	// script, as defined by RFC 3875 § 5, whose output is a complete
+	// HTTP/1 response. Executables whose base name begins with "nph-"
+	// are NPH scripts regardless of NPH.
+	//
+	// An NPH script's output is passed through unchanged on the
+	// hijacked client connection, which is closed when the script
+	// exits. When the connection cannot be hijacked, as with HTTP/2,
+	// the output is parsed instead, and its status, end-to-end
+	// headers, body and trailers are sent as the response.
+	NPH bool
+
+	// MaxSpoolSize, if positive, enables requests whose body has no
+	// declared length, such as chunked HTTP/1.1 uploads and HTTP/2
+	// requests without a Content-Length. CGI requires the length of
+	// the body in CONTENT_LENGTH, so such a body is read in full
+	// before the child process starts. Bodies longer than
+	// MaxSpoolSize bytes are rejected with 413 (Request Entity Too
+	// Large).
+	//
+	// If MaxSpoolSize is zero, chunked request bodies are rejected
+	// with 400 (Bad Request).
+	MaxSpoolSize int64
+
+	// SpoolMemSize is the size of the largest body spooled in
+	// memory; longer bodies are written to a temporary file in
+	// SpoolDir. If zero, DefaultSpoolMemSize is used.
+	SpoolMemSize int64
+
+	// SpoolDir is the directory for temporary files holding spooled
+	// bodies. If empty, os.TempDir is used.
+	SpoolDir string
+
+	// MaxConcurrent, if positive, limits the number of child
+	// processes running at once. A request arriving when the limit
+	// is reached waits up to QueueTimeout for a child to exit, and is
+	// then rejected with 503 (Service Unavailable).
+	MaxConcurrent int
+	QueueTimeout  time.Duration
+
+	// When the request's context is done, as when the client goes
+	// away, the child process is sent SIGTERM and, if it has not
+	// exited KillDelay later, it is killed. Timeout, if positive,
+	// limits the time a child may run by adding a deadline to the
+	// context. If zero, KillDelay is 5 seconds; it also bounds the
+	// wait for the request body and the child's output once the
+	// child has exited. On systems without SIGTERM the child is
+	// killed at once.
+	Timeout   time.Duration
+	KillDelay time.Duration
+
+	// Limits, if non-nil, are resource limits for child processes.
+	Limits *Limits
+
+	// Credential, if non-nil, is the user and groups child processes
+	// run as, typically to drop the privileges of a host running as
+	// root.
+	Credential *Credential
+
+	semOnce sync.Once
+	sem     chan struct{} // holds a value for each running child
 }
 
+// DefaultSpoolMemSize is the default value of [Handler.SpoolMemSize].
+const DefaultSpoolMemSize = 64 << 10
+
 func (h *Handler) stderr() io.Writer {
 	if h.Stderr != nil {
 		return h.Stderr
@@ -88,100 +156,57 @@ func (h *Handler) stderr() io.Writer {
 	return os.Stderr
 }
 
-// removeLeadingDuplicates remove leading duplicate in environments.
-// It's possible to override environment like following.
-//
-//	cgi.Handler{
-//	  ...
-//	  Env: []string{"SCRIPT_FILENAME=foo.php"},
-//	}
-func removeLeadingDuplicates(env []string) (ret []string) {
-	for i, e := range env {
-		found := false
-		if eq := strings.IndexByte(e, '='); eq != -1 {
-			keq := e[:eq+1] // "key="
-			for _, e2 := range env[i+1:] {
-				if strings.HasPrefix(e2, keq) {
-					found = true
-					break
-				}
-			}
-		}
-		if !found {
-			ret = append(ret, e)
-		}
-	}
-	return
+func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	h.serve(rw, req, script{path: h.Path, root: h.Root})
 }
 
-func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
-	if len(req.TransferEncoding) > 0 && req.TransferEncoding[0] == "chunked" {
+// A script is the program a Handler runs for a request.
+type script struct {
+	path   string // the executable or, if interp is set, the script file
+	root   string // URI prefix of the script, its SCRIPT_NAME
+	interp string // interpreter running the script file, or ""
+}
+
+func (h *Handler) serve(rw http.ResponseWriter, req *http.Request, s script) {
+	chunked := len(req.TransferEncoding) > 0 && req.TransferEncoding[0] == "chunked"
+	spooled := h.MaxSpoolSize > 0 && (chunked || req.ContentLength < 0)
+	if spooled {
+		body, n, err := h.spoolBody(rw, req.Body)
+		if err != nil {
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				rw.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			rw.WriteHeader(http.StatusBadRequest)
+			h.printf("cgi: error reading request body: %v", err)
+			return
+		}
+		defer body.Close()
+		req = req.Clone(req.Context())
+		req.Body = body
+		req.ContentLength = n
+		req.TransferEncoding = nil
+	} else if chunked {
 		rw.WriteHeader(http.StatusBadRequest)
 		rw.Write([]byte("Chunked request bodies are not supported by CGI."))
 		return
 	}
 
-	root := strings.TrimRight(h.Root, "/")
-	pathInfo := strings.TrimPrefix(req.URL.Path, root)
-
-	port := "80"
-	if req.TLS != nil {
-		port = "443"
-	}
-	if matches := trailingPort.FindStringSubmatch(req.Host); len(matches) != 0 {
-		port = matches[1]
-	}
-
-	env := []string{
-		"SERVER_SOFTWARE=go",
-		"SERVER_PROTOCOL=HTTP/1.1",
-		"HTTP_HOST=" + req.Host,
-		"GATEWAY_INTERFACE=CGI/1.1",
-		"REQUEST_METHOD=" + req.Method,
-		"QUERY_STRING=" + req.URL.RawQuery,
-		"REQUEST_URI=" + req.URL.RequestURI(),
-		"PATH_INFO=" + pathInfo,
-		"SCRIPT_NAME=" + root,
-		"SCRIPT_FILENAME=" + h.Path,
-		"SERVER_PORT=" + port,
-	}
-
-	if remoteIP, remotePort, err := net.SplitHostPort(req.RemoteAddr); err == nil {
-		env = append(env, "REMOTE_ADDR="+remoteIP, "REMOTE_HOST="+remoteIP, "REMOTE_PORT="+remotePort)
-	} else {
-		// could not parse ip:port, let's use whole RemoteAddr and leave REMOTE_PORT undefined
-		env = append(env, "REMOTE_ADDR="+req.RemoteAddr, "REMOTE_HOST="+req.RemoteAddr)
-	}
-
-	if hostDomain, _, err := net.SplitHostPort(req.Host); err == nil {
-		env = append(env, "SERVER_NAME="+hostDomain)
-	} else {
-		env = append(env, "SERVER_NAME="+req.Host)
-	}
-
-	if req.TLS != nil {
-		env = append(env, "HTTPS=on")
+	if !h.acquire(req.Context()) {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		return
 	}
+	defer h.release()
 
-	for k, v := range req.Header {
-		k = strings.Map(upperCaseAndUnderscore, k)
-		if k == "PROXY" {
-			// See Issue 16405
-			continue
-		}
-		joinStr := ", "
-		if k == "COOKIE" {
-			joinStr = "; "
-		}
-		env = append(env, "HTTP_"+k+"="+strings.Join(v, joinStr))
+	ctx := req.Context()
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
 	}
 
-	if req.ContentLength > 0 {
-		env = append(env, fmt.Sprintf("CONTENT_LENGTH=%d", req.ContentLength))
-	}
-	if ctype := req.Header.Get("Content-Type"); ctype != "" {
-		env = append(env, "CONTENT_TYPE="+ctype)
-	}
+	env := cgienv.Request(req, s.root, s.path)
 
 	envPath := os.Getenv("PATH")
 	if envPath == "" {
@@ -205,18 +230,23 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		env = append(env, h.Env...)
 	}
 
-	env = removeLeadingDuplicates(env)
+	env = cgienv.RemoveLeadingDuplicates(env)
 
 	var cwd, path string
 	if h.Dir != "" {
-		path = h.Path
+		path = s.path
 		cwd = h.Dir
 	} else {
-		cwd, path = filepath.Split(h.Path)
+		cwd, path = filepath.Split(s.path)
 	}
 	if cwd == "" {
 		cwd = "."
 	}
+	args := append([]string{s.path}, h.Args...)
+	if s.interp != "" {
+		path = s.interp
+		args = append([]string{s.interp, s.path}, h.Args...)
+	}
 
 	internalError := func(err error) {
 		rw.WriteHeader(http.StatusInternalServerError)
@@ -225,7 +255,7 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 
 	cmd := &exec.Cmd{
 		Path:   path,
-		Args:   append([]string{h.Path}, h.Args...),
+		Args:   args,
 		Dir:    cwd,
 		Env:    env,
 		Stderr: h.stderr(),
@@ -233,6 +263,43 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	if req.ContentLength != 0 {
 		cmd.Stdin = req.Body
 	}
+
+	var err error
+	nph := h.nph(s.path)
+	var conn net.Conn
+	if nph {
+		var brw *bufio.ReadWriter
+		conn, brw, err = http.NewResponseController(rw).Hijack()
+		if err == nil {
+			defer conn.Close()
+			internalError = func(err error) {
+				io.WriteString(conn, "HTTP/1.1 500 Internal Server Error\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+				h.printf("CGI error: %v", err)
+			}
+			if !spooled && req.ContentLength > 0 {
+				// The server has not read the body, which follows
+				// the header on the hijacked connection.
+				if req.Header.Get("Expect") == "100-continue" {
+					io.WriteString(conn, "HTTP/1.1 100 Continue\r\n\r\n")
+				}
+				cmd.Stdin = io.LimitReader(brw.Reader, req.ContentLength)
+			}
+		}
+		// Otherwise, as with HTTP/2, the response is parsed.
+	}
+	attr, err := sysProcAttr(h.Credential)
+	if err != nil {
+		internalError(err)
+		return
+	}
+	cmd.SysProcAttr = attr
+	if err := limitCmd(cmd, h.Limits); err != nil {
+		internalError(err)
+		return
+	}
+	// Don't wait for a request body or grandchildren holding the
+	// child's output once the child has exited.
+	cmd.WaitDelay = h.killDelay()
 	stdoutRead, err := cmd.StdoutPipe()
 	if err != nil {
 		internalError(err)
@@ -247,9 +314,23 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	if hook := testHookStartProcess; hook != nil {
 		hook(cmd.Process)
 	}
+	defer h.stopOnDone(ctx, cmd.Process)()
 	defer cmd.Wait()
 	defer stdoutRead.Close()
 
+	if nph {
+		if conn != nil {
+			// The child's output is the whole response.
+			if _, err := io.Copy(conn, stdoutRead); err != nil {
+				h.printf("cgi: copy error: %v", err)
+				cmd.Process.Kill()
+			}
+			return
+		}
+		h.serveParsedNPH(rw, req, stdoutRead, cmd.Process)
+		return
+	}
+
 	linebody := bufio.NewReaderSize(stdoutRead, 1024)
 	headers := make(http.Header)
 	statusCode := 0
@@ -303,6 +384,11 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		}
 	}
 	if headerLines == 0 || !sawBlankLine {
+		if ctx.Err() == context.DeadlineExceeded {
+			rw.WriteHeader(http.StatusGatewayTimeout)
+			h.printf("cgi: timed out waiting for headers")
+			return
+		}
 		rw.WriteHeader(http.StatusInternalServerError)
 		h.printf("cgi: no headers")
 		return
@@ -328,6 +414,16 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		statusCode = http.StatusOK
 	}
 
+	// A child sending trailers uses chunked encoding, as documented
+	// by Serve: decode the body and read the trailer that follows.
+	var body io.Reader = linebody
+	chunkedOut := headers.Get("Transfer-Encoding") == "chunked"
+	if chunkedOut {
+		headers.Del("Transfer-Encoding")
+		headers.Del("Content-Length")
+		body = httputil.NewChunkedReader(linebody)
+	}
+
 	// Copy headers to rw's headers, after we've decided not to
 	// go into handleInternalRedirect, which won't want its rw
 	// headers to have been touched.
@@ -339,7 +435,14 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 
 	rw.WriteHeader(statusCode)
 
-	_, err = io.Copy(rw, linebody)
+	err = cgienv.CopyFlush(rw, body)
+	if err == nil && chunkedOut {
+		var trailer textproto.MIMEHeader
+		trailer, err = textproto.NewReader(linebody).ReadMIMEHeader()
+		for k, vv := range trailer {
+			rw.Header()[http.TrailerPrefix+k] = vv
+		}
+	}
 	if err != nil {
 		h.printf("cgi: copy error: %v", err)
 		// And kill the child CGI process so we don't hang on
@@ -352,6 +455,166 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// spoolBody reads body, which must not exceed h.MaxSpoolSize bytes,
+// into memory or a temporary file, and returns a reader for it and
+// its length. Closing the reader removes any temporary file.
+func (h *Handler) spoolBody(rw http.ResponseWriter, body io.ReadCloser) (io.ReadCloser, int64, error) {
+	if body == nil || body == http.NoBody {
+		return http.NoBody, 0, nil
+	}
+	r := http.MaxBytesReader(rw, body, h.MaxSpoolSize)
+	memSize := h.SpoolMemSize
+	if memSize <= 0 {
+		memSize = DefaultSpoolMemSize
+	}
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, r, memSize+1)
+	if err == io.EOF {
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), n, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	f, err := os.CreateTemp(h.SpoolDir, "cgi-body-")
+	if err != nil {
+		return nil, 0, err
+	}
+	tf := &tempFile{f}
+	if _, err := buf.WriteTo(f); err != nil {
+		tf.Close()
+		return nil, 0, err
+	}
+	m, err := io.Copy(f, r)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		tf.Close()
+		return nil, 0, err
+	}
+	return tf, n + m, nil
+}
+
+// A tempFile is a temporary file removed when it is closed.
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
+}
+
+// nph reports whether the script at path is run by h as a non-parsed
+// header (NPH) script.
+func (h *Handler) nph(path string) bool {
+	return h.NPH || strings.HasPrefix(filepath.Base(path), "nph-")
+}
+
+// serveParsedNPH serves the output of an NPH script, which is a
+// complete HTTP/1 response, by parsing it, when the connection cannot
+// be hijacked to pass the output through.
+func (h *Handler) serveParsedNPH(rw http.ResponseWriter, req *http.Request, stdout io.Reader, p *os.Process) {
+	br := bufio.NewReader(stdout)
+	var (
+		res        *http.Response
+		connection []string
+	)
+	for {
+		var err error
+		res, connection, err = readNPHResponse(br, req)
+		if err != nil {
+			rw.WriteHeader(http.StatusInternalServerError)
+			h.printf("cgi: error reading NPH response: %v", err)
+			return
+		}
+		if res.StatusCode >= 200 || res.StatusCode == http.StatusSwitchingProtocols {
+			break
+		}
+		// Forward informational responses, such as 103 Early Hints.
+		copyEndToEndHeader(rw.Header(), res.Header, connection)
+		rw.WriteHeader(res.StatusCode)
+		clear(rw.Header())
+	}
+	defer res.Body.Close()
+
+	copyEndToEndHeader(rw.Header(), res.Header, connection)
+	rw.WriteHeader(res.StatusCode)
+	if _, err := io.Copy(rw, res.Body); err != nil {
+		h.printf("cgi: copy error: %v", err)
+		p.Kill()
+		return
+	}
+	for k, vv := range res.Trailer {
+		rw.Header()[http.TrailerPrefix+k] = vv
+	}
+}
+
+// maxNPHHeaderBytes limits the size of the header of a parsed NPH
+// response.
+const maxNPHHeaderBytes = 1 << 20
+
+// readNPHResponse reads a response from br, returning it and the
+// values of its Connection header, which http.ReadResponse removes if
+// they include "close".
+func readNPHResponse(br *bufio.Reader, req *http.Request) (*http.Response, []string, error) {
+	var head bytes.Buffer
+	for {
+		line, err := br.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			err = nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		head.Write(line)
+		if head.Len() > maxNPHHeaderBytes {
+			return nil, nil, errors.New("response header too large")
+		}
+		if head.Len() > len(line) && (string(line) == "\r\n" || string(line) == "\n") {
+			break
+		}
+	}
+	tp := textproto.NewReader(bufio.NewReader(bytes.NewReader(head.Bytes())))
+	tp.ReadLine()
+	hdr, _ := tp.ReadMIMEHeader()
+	res, err := http.ReadResponse(bufio.NewReader(io.MultiReader(&head, br)), req)
+	return res, hdr["Connection"], err
+}
+
+// hopHeaders are the hop-by-hop headers, which describe a single
+// connection and are not copied from a parsed NPH response.
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// copyEndToEndHeader copies src to dst, omitting hop-by-hop headers
+// and those named in the values of the Connection header.
+func copyEndToEndHeader(dst, src http.Header, connection []string) {
+	skip := make(map[string]bool)
+	for _, k := range hopHeaders {
+		skip[k] = true
+	}
+	for _, v := range connection {
+		for f := range strings.SplitSeq(v, ",") {
+			skip[http.CanonicalHeaderKey(textproto.TrimString(f))] = true
+		}
+	}
+	for k, vv := range src {
+		if !skip[k] {
+			dst[k] = append(dst[k], vv...)
+		}
+	}
+}
+
 func (h *Handler) printf(format string, v ...any) {
 	if h.Logger != nil {
 		h.Logger.Printf(format, v...)
@@ -390,20 +653,4 @@ func (h *Handler) handleInternalRedirect(rw http.ResponseWriter, req *http.Reque
 	h.PathLocationHandler.ServeHTTP(rw, newReq)
 }
 
-func upperCaseAndUnderscore(r rune) rune {
-	switch {
-	case r >= 'a' && r <= 'z':
-		return r - ('a' - 'A')
-	case r == '-':
-		return '_'
-	case r == '=':
-		// Maybe not part of the CGI 'spec' but would mess up
-		// the environment in any case, as Go represents the
-		// environment as a slice of "key=value" strings.
-		return '_'
-	}
-	// TODO: other transformations in spec or practice?
-	return r
-}
-
 var testHookStartProcess func(*os.Process) // nil except for some tests
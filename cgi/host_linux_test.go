@@ -0,0 +1,91 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgi
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/johnsiilver/http/httptest"
+	"github.com/johnsiilver/http/internal/testenv"
+)
+
+func TestLimits(t *testing.T) {
+	testenv.MustHaveExec(t)
+
+	h := &Handler{
+		Path: os.Args[0],
+		Root: "/test.go",
+		Limits: &Limits{
+			CPUTime:   1500 * time.Millisecond,
+			OpenFiles: 64,
+		},
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "http://example.com/test.go?proc-limits=1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want 200", rec.Code)
+	}
+	limits := rec.Body.String()
+	for _, want := range []string{
+		`Max cpu time\s+2\s+3\s`,
+		`Max open files\s+64\s+64\s`,
+	} {
+		if !regexp.MustCompile(want).MatchString(limits) {
+			t.Errorf("child limits do not match %q:\n%s", want, limits)
+		}
+	}
+}
+
+func TestCredential(t *testing.T) {
+	testenv.MustHaveExec(t)
+	if os.Getuid() != 0 {
+		t.Skip("changing user requires root")
+	}
+
+	// The child must be able to run the test binary as another user.
+	dir, err := os.MkdirTemp("", "cgi-credential-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Chmod(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	exe := filepath.Join(dir, "cgi.test")
+	src, err := os.Open(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(exe, os.O_CREATE|os.O_WRONLY, 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.Copy(dst, src)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &Handler{
+		Path:       exe,
+		Root:       "/test.go",
+		Dir:        dir,
+		Credential: &Credential{UID: 65534, GID: 65534},
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "http://example.com/test.go?ids=1", nil))
+	if got, want := rec.Body.String(), "uid=65534\ngid=65534\n"; got != want {
+		t.Errorf("child reported %q (status %d); want %q", got, rec.Code, want)
+	}
+}
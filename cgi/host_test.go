@@ -0,0 +1,439 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Tests for package cgi
+
+package cgi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnsiilver/http/httptest"
+	"github.com/johnsiilver/http/internal/testenv"
+)
+
+func TestMain(m *testing.M) {
+	// The test binary is also the CGI program run by the tests.
+	if os.Getenv("SERVER_SOFTWARE") != "" {
+		cgiMain()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// newChunkedRequest returns a form POST whose body has no declared
+// length, as received from a chunked HTTP/1.1 request.
+func newChunkedRequest(body string) *http.Request {
+	req := httptest.NewRequest("POST", "http://example.com/test.go", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.ContentLength = -1
+	req.TransferEncoding = []string{"chunked"}
+	return req
+}
+
+func newRequest(httpreq string) *http.Request {
+	buf := bufio.NewReader(strings.NewReader(httpreq))
+	req, err := http.ReadRequest(buf)
+	if err != nil {
+		panic("cgi: bogus http request in test: " + httpreq)
+	}
+	req.RemoteAddr = "1.2.3.4:1234"
+	return req
+}
+
+func runCgiTest(t *testing.T, h *Handler,
+	httpreq string,
+	expectedMap map[string]string, checks ...func(reqInfo map[string]string)) *httptest.ResponseRecorder {
+	rw := httptest.NewRecorder()
+	req := newRequest(httpreq)
+	h.ServeHTTP(rw, req)
+	runResponseChecks(t, rw, expectedMap, checks...)
+	return rw
+}
+
+func runResponseChecks(t *testing.T, rw *httptest.ResponseRecorder,
+	expectedMap map[string]string, checks ...func(reqInfo map[string]string)) {
+
+	// Make a map to hold the test map that the CGI returns.
+	m := make(map[string]string)
+	m["_body"] = rw.Body.String()
+	linesRead := 0
+readlines:
+	for {
+		line, err := rw.Body.ReadString('\n')
+		switch {
+		case err == io.EOF:
+			break readlines
+		case err != nil:
+			t.Fatalf("unexpected error reading from CGI: %v", err)
+		}
+		linesRead++
+		trimmedLine := strings.TrimRight(line, "\r\n")
+		k, v, ok := strings.Cut(trimmedLine, "=")
+		if !ok {
+			t.Fatalf("Unexpected response from invalid line number %v: %q; existing map=%v",
+				linesRead, line, m)
+		}
+		m[k] = v
+	}
+
+	for key, expected := range expectedMap {
+		got := m[key]
+		if key == "cwd" {
+			// For Windows. golang.org/issue/4645.
+			fi1, _ := os.Stat(got)
+			fi2, _ := os.Stat(expected)
+			if os.SameFile(fi1, fi2) {
+				got = expected
+			}
+		}
+		if got != expected {
+			t.Errorf("for key %q got %q; expected %q", key, got, expected)
+		}
+	}
+	for _, check := range checks {
+		check(m)
+	}
+}
+
+func TestSpooledBody(t *testing.T) {
+	testenv.MustHaveExec(t)
+
+	for _, tt := range []struct {
+		name    string
+		memSize int64
+	}{
+		{"memory", 0},
+		{"file", 4},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			h := &Handler{
+				Path:         os.Args[0],
+				Root:         "/test.go",
+				MaxSpoolSize: 1 << 20,
+				SpoolMemSize: tt.memSize,
+				SpoolDir:     dir,
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, newChunkedRequest("a=b&foo=bar"))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d; want 200; body:\n%s", rec.Code, rec.Body)
+			}
+			runResponseChecks(t, rec, map[string]string{
+				"param-a":            "b",
+				"param-foo":          "bar",
+				"env-CONTENT_LENGTH": "11",
+			})
+			if files, _ := os.ReadDir(dir); len(files) != 0 {
+				t.Errorf("spool directory not empty after request: %v", files)
+			}
+		})
+	}
+}
+
+func TestSpooledBodyTooLarge(t *testing.T) {
+	h := &Handler{
+		Path:         os.Args[0],
+		Root:         "/test.go",
+		MaxSpoolSize: 4,
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newChunkedRequest("a=b&foo=bar"))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d; want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestChunkedBodyRejected(t *testing.T) {
+	h := &Handler{
+		Path: os.Args[0],
+		Root: "/test.go",
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newChunkedRequest("a=b"))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d; want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestMaxConcurrent(t *testing.T) {
+	testenv.MustHaveExec(t)
+
+	started := make(chan bool, 10)
+	testHookStartProcess = func(*os.Process) { started <- true }
+	defer func() { testHookStartProcess = nil }()
+
+	// serve runs a request in the background and returns the channel
+	// receiving its status.
+	serve := func(h *Handler, url string) chan int {
+		code := make(chan int, 1)
+		go func() {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+			code <- rec.Code
+		}()
+		return code
+	}
+
+	t.Run("rejected", func(t *testing.T) {
+		h := &Handler{Path: os.Args[0], Root: "/test.go", MaxConcurrent: 1, QueueTimeout: 50 * time.Millisecond}
+		first := serve(h, "http://example.com/test.go?sleep=500ms&no-body=1")
+		<-started
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "http://example.com/test.go?no-body=1", nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("second request: status = %d; want 503", rec.Code)
+		}
+		if code := <-first; code != http.StatusOK {
+			t.Errorf("first request: status = %d; want 200", code)
+		}
+	})
+
+	t.Run("queued", func(t *testing.T) {
+		h := &Handler{Path: os.Args[0], Root: "/test.go", MaxConcurrent: 1, QueueTimeout: 10 * time.Second}
+		first := serve(h, "http://example.com/test.go?sleep=200ms&no-body=1")
+		<-started
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "http://example.com/test.go?no-body=1", nil))
+		<-started
+		if rec.Code != http.StatusOK {
+			t.Errorf("second request: status = %d; want 200", rec.Code)
+		}
+		if code := <-first; code != http.StatusOK {
+			t.Errorf("first request: status = %d; want 200", code)
+		}
+	})
+}
+
+func TestTimeout(t *testing.T) {
+	testenv.MustHaveExec(t)
+
+	for _, tt := range []struct {
+		name    string
+		url     string
+		minTime time.Duration // on Unix
+	}{
+		{"terminated", "/test.go?sleep=10s", 200 * time.Millisecond},
+		{"killed", "/test.go?sleep=10s&ignore-sigterm=1", 500 * time.Millisecond},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{
+				Path:      os.Args[0],
+				Root:      "/test.go",
+				Timeout:   200 * time.Millisecond,
+				KillDelay: 300 * time.Millisecond,
+			}
+			start := time.Now()
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("GET", "http://example.com"+tt.url, nil))
+			elapsed := time.Since(start)
+			if rec.Code != http.StatusGatewayTimeout {
+				t.Errorf("status = %d; want 504", rec.Code)
+			}
+			if elapsed > 5*time.Second {
+				t.Errorf("request took %v; want the child stopped after the timeout", elapsed)
+			}
+			if runtime.GOOS != "windows" && runtime.GOOS != "plan9" && elapsed < tt.minTime {
+				t.Errorf("request took %v; want at least %v", elapsed, tt.minTime)
+			}
+		})
+	}
+
+	for _, timeout := range []time.Duration{time.Minute, 0} {
+		t.Run(fmt.Sprintf("canceled/timeout=%v", timeout), func(t *testing.T) {
+			h := &Handler{Path: os.Args[0], Root: "/test.go", Timeout: timeout}
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			req := httptest.NewRequestWithContext(ctx, "GET", "http://example.com/test.go?sleep=10s", nil)
+			start := time.Now()
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("request took %v; want the child stopped when the request was canceled", elapsed)
+			}
+		})
+	}
+}
+
+func TestNPH(t *testing.T) {
+	testenv.MustHaveExec(t)
+
+	h := &Handler{
+		Path: os.Args[0],
+		Root: "/nph.cgi",
+		NPH:  true,
+	}
+	check := func(t *testing.T, res *http.Response, body string) {
+		t.Helper()
+		if res.StatusCode != http.StatusCreated {
+			t.Errorf("status = %d; want 201", res.StatusCode)
+		}
+		if got := res.Header.Get("X-NPH"); got != "yes" {
+			t.Errorf("X-NPH = %q; want yes", got)
+		}
+		if body != "got: ping" {
+			t.Errorf("body = %q; want %q", body, "got: ping")
+		}
+		if got := res.Trailer.Get("X-Sum"); got != "4" {
+			t.Errorf("trailer X-Sum = %q; want 4", got)
+		}
+	}
+	post := func(t *testing.T, c *http.Client, url string) {
+		t.Helper()
+		res, err := c.Post(url+"/nph.cgi", "text/plain", strings.NewReader("ping"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, res, string(b))
+	}
+
+	t.Run("hijacked", func(t *testing.T) {
+		ts := httptest.NewServer(h)
+		defer ts.Close()
+		post(t, ts.Client(), ts.URL)
+	})
+
+	t.Run("http2", func(t *testing.T) {
+		ts := httptest.NewUnstartedServer(h)
+		ts.EnableHTTP2 = true
+		ts.StartTLS()
+		defer ts.Close()
+		post(t, ts.Client(), ts.URL)
+	})
+
+	t.Run("parsed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "http://example.com/nph.cgi", strings.NewReader("ping")))
+		res := rec.Result()
+		check(t, res, rec.Body.String())
+		if len(rec.Interim) != 1 || rec.Interim[0].Code != http.StatusEarlyHints {
+			t.Errorf("interim responses = %v; want one 103", rec.Interim)
+		}
+		for _, k := range []string{"Connection", "X-Hop", "Transfer-Encoding", "Trailer"} {
+			if v, ok := res.Header[k]; ok {
+				t.Errorf("hop-by-hop header %s: %q was copied from the NPH response", k, v)
+			}
+		}
+	})
+}
+
+func TestNPHByName(t *testing.T) {
+	for path, want := range map[string]bool{
+		"/cgi-bin/nph-status": true,
+		"nph-status.pl":       true,
+		"/cgi-bin/status":     false,
+		"/nph-dir/status":     false,
+	} {
+		if got := (&Handler{Path: path}).nph(path); got != want {
+			t.Errorf("Handler{Path: %q} is NPH = %v; want %v", path, got, want)
+		}
+	}
+}
+
+func TestServeFlush(t *testing.T) {
+	testenv.MustHaveExec(t)
+
+	ts := httptest.NewServer(&Handler{Path: os.Args[0], Root: "/test.go"})
+	defer ts.Close()
+	res, err := ts.Client().Get(ts.URL + "/test.go?flush=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	br := bufio.NewReader(res.Body)
+	first, err := br.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	rest, err := io.ReadAll(br)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != "first\n" || string(rest) != "second\n" {
+		t.Errorf("body = %q + %q; want %q + %q", first, rest, "first\n", "second\n")
+	}
+	// The child sleeps between the two writes; if the first was not
+	// flushed, both arrive together.
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("second write arrived %v after the first; want the first flushed before the child's sleep", elapsed)
+	}
+}
+
+func TestServeTrailers(t *testing.T) {
+	testenv.MustHaveExec(t)
+
+	h := &Handler{Path: os.Args[0], Root: "/test.go"}
+	check := func(t *testing.T, res *http.Response, body string) {
+		t.Helper()
+		if body != "trailer body" {
+			t.Errorf("body = %q; want %q", body, "trailer body")
+		}
+		if got := res.Trailer.Get("X-Sum"); got != "12" {
+			t.Errorf("trailer X-Sum = %q; want 12", got)
+		}
+		if got := res.Trailer.Get("X-Late"); got != "late" {
+			t.Errorf("trailer X-Late = %q; want late", got)
+		}
+	}
+
+	t.Run("recorder", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "http://example.com/test.go?trailer=1", nil))
+		res := rec.Result()
+		check(t, res, rec.Body.String())
+		if v, ok := res.Header["Transfer-Encoding"]; ok {
+			t.Errorf("Transfer-Encoding: %q was copied from the CGI response", v)
+		}
+	})
+
+	t.Run("server", func(t *testing.T) {
+		ts := httptest.NewServer(h)
+		defer ts.Close()
+		res, err := ts.Client().Get(ts.URL + "/test.go?trailer=1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, res, string(b))
+	})
+}
+
+func TestServeContextTerminated(t *testing.T) {
+	testenv.MustHaveExec(t)
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		t.Skipf("no SIGTERM on %s", runtime.GOOS)
+	}
+
+	var stderr bytes.Buffer
+	h := &Handler{
+		Path:    os.Args[0],
+		Root:    "/test.go",
+		Timeout: 200 * time.Millisecond,
+		Stderr:  &stderr,
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://example.com/test.go?wait-context=1", nil))
+	if want := "context: " + ErrTerminated.Error(); !strings.Contains(stderr.String(), want) {
+		t.Errorf("child stderr = %q; want %q", stderr.String(), want)
+	}
+}
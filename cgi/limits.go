@@ -0,0 +1,113 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Limits on the child processes run by Handler.
+
+package cgi
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// Limits are resource limits for a child process. A zero field means
+// no limit. Limits are supported only on Linux.
+//
+// The limits are set before the child's program starts, by running it
+// from /bin/sh after setting them with ulimit, so they also apply to
+// any processes it starts. The child cannot raise them.
+type Limits struct {
+	// CPUTime limits the CPU time of the child, rounded up to
+	// whole seconds (RLIMIT_CPU). The child receives SIGXCPU when
+	// it is reached and is killed a second later.
+	CPUTime time.Duration
+
+	// Memory limits the size in bytes of the child's virtual
+	// address space (RLIMIT_AS), rounded down to whole KiB.
+	Memory uint64
+
+	// OpenFiles limits the number of files the child may have open
+	// (RLIMIT_NOFILE).
+	OpenFiles uint64
+}
+
+// A Credential is the user and groups a child process runs as.
+// Running a child as another user requires privileges; credentials
+// are supported only on Unix systems.
+type Credential struct {
+	UID    uint32
+	GID    uint32
+	Groups []uint32 // supplementary group IDs; nil for none
+}
+
+// defaultKillDelay is the default value of Handler.KillDelay.
+const defaultKillDelay = 5 * time.Second
+
+func (h *Handler) killDelay() time.Duration {
+	if h.KillDelay > 0 {
+		return h.KillDelay
+	}
+	return defaultKillDelay
+}
+
+// acquire waits for one of the h.MaxConcurrent slots for running a
+// child to be free, as configured by h.QueueTimeout, and reports
+// whether it got one. Each successful call must be matched by a call
+// to release.
+func (h *Handler) acquire(ctx context.Context) bool {
+	if h.MaxConcurrent <= 0 {
+		return true
+	}
+	h.semOnce.Do(func() { h.sem = make(chan struct{}, h.MaxConcurrent) })
+	select {
+	case h.sem <- struct{}{}:
+		return true
+	default:
+	}
+	if h.QueueTimeout <= 0 {
+		return false
+	}
+	t := time.NewTimer(h.QueueTimeout)
+	defer t.Stop()
+	select {
+	case h.sem <- struct{}{}:
+		return true
+	case <-t.C:
+	case <-ctx.Done():
+	}
+	return false
+}
+
+func (h *Handler) release() {
+	if h.MaxConcurrent > 0 {
+		<-h.sem
+	}
+}
+
+// stopOnDone arranges for p to be stopped when ctx is done: it is sent
+// a termination signal and, if it is still running KillDelay later,
+// killed. Calling stop cancels the arrangement.
+func (h *Handler) stopOnDone(ctx context.Context, p *os.Process) (stop func()) {
+	var (
+		mu   sync.Mutex
+		kill *time.Timer
+	)
+	stopWatch := context.AfterFunc(ctx, func() {
+		h.printf("cgi: stopping child process %d: %v", p.Pid, ctx.Err())
+		terminate(p)
+		mu.Lock()
+		defer mu.Unlock()
+		kill = time.AfterFunc(h.killDelay(), func() { p.Kill() })
+	})
+	return func() {
+		stopWatch()
+		mu.Lock()
+		defer mu.Unlock()
+		if kill != nil {
+			kill.Stop()
+		}
+	}
+}
@@ -0,0 +1,25 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !unix
+
+package cgi
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+func sysProcAttr(c *Credential) (*syscall.SysProcAttr, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return nil, errors.New("cgi: Credential is not supported on this system")
+}
+
+// terminate kills p: there is no portable way to ask it to exit.
+func terminate(p *os.Process) {
+	p.Kill()
+}
@@ -0,0 +1,31 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package cgi
+
+import (
+	"os"
+	"syscall"
+)
+
+// sysProcAttr returns the attributes for running a child process as c.
+func sysProcAttr(c *Credential) (*syscall.SysProcAttr, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return &syscall.SysProcAttr{
+		Credential: &syscall.Credential{
+			Uid:    c.UID,
+			Gid:    c.GID,
+			Groups: c.Groups,
+		},
+	}, nil
+}
+
+// terminate asks p to exit.
+func terminate(p *os.Process) {
+	p.Signal(syscall.SIGTERM)
+}
@@ -0,0 +1,51 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgi
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// limitCmd arranges for cmd, which has not been started, to run with
+// the limits l.
+//
+// SysProcAttr has no way to set resource limits between fork and
+// exec, and setting them with prlimit once the child has started
+// leaves it running without limits for a while, as are any processes
+// it starts meanwhile. So cmd is run by a shell, which sets the limits
+// on itself with ulimit and then executes the program in its place.
+func limitCmd(cmd *exec.Cmd, l *Limits) error {
+	if l == nil || *l == (Limits{}) {
+		return nil
+	}
+	var script strings.Builder
+	if l.CPUTime > 0 {
+		secs := int64((l.CPUTime + time.Second - 1) / time.Second)
+		// The soft limit sends SIGXCPU; the hard limit, SIGKILL. The
+		// hard limit cannot be lowered below the soft one, so the
+		// soft limit is set first.
+		fmt.Fprintf(&script, "ulimit -S -t %d && ulimit -H -t %d && ", secs, secs+1)
+	}
+	if l.Memory > 0 {
+		fmt.Fprintf(&script, "ulimit -v %d && ", max(l.Memory/1024, 1))
+	}
+	if l.OpenFiles > 0 {
+		fmt.Fprintf(&script, "ulimit -n %d && ", l.OpenFiles)
+	}
+	script.WriteString(`exec "$0" "$@"`)
+
+	// The shell looks up a program name without a slash in PATH, while
+	// cmd.Path is relative to cmd.Dir.
+	prog := cmd.Path
+	if !strings.Contains(prog, "/") {
+		prog = "./" + prog
+	}
+	cmd.Args = append([]string{"sh", "-c", script.String(), prog}, cmd.Args[1:]...)
+	cmd.Path = "/bin/sh"
+	return nil
+}
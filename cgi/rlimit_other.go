@@ -0,0 +1,19 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package cgi
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func limitCmd(cmd *exec.Cmd, l *Limits) error {
+	if l == nil || *l == (Limits{}) {
+		return nil
+	}
+	return errors.New("cgi: Limits are not supported on this system")
+}
@@ -0,0 +1,285 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// A config describes a benchmark run.
+type config struct {
+	URL    string
+	Method string
+	Header http.Header
+	Body   []byte
+
+	Concurrency int           // number of workers
+	Rate        float64       // requests per second; 0 for as fast as possible
+	Requests    int           // total requests; 0 for no limit
+	Duration    time.Duration // run time; 0 for no limit
+
+	Timeout           time.Duration // per request
+	Insecure          bool          // skip TLS verification
+	DisableKeepAlives bool
+	HTTP2             bool
+}
+
+// Phases of a request, as reported by httptrace.
+const (
+	phaseDNS = iota
+	phaseConnect
+	phaseTLS
+	phaseTTFB     // request written to first response byte
+	phaseTransfer // first response byte to end of body
+	phaseTotal
+	numPhases
+)
+
+var phaseNames = [numPhases]string{"dns", "connect", "tls", "ttfb", "transfer", "total"}
+
+// stats are the statistics collected by a worker, and merged into
+// the run's result.
+type stats struct {
+	phases   [numPhases]histogram
+	status   map[int]int64
+	errors   map[string]int64
+	requests int64
+	bytes    int64
+	newConns int64
+	reused   int64
+}
+
+func newStats() *stats {
+	return &stats{status: make(map[int]int64), errors: make(map[string]int64)}
+}
+
+func (s *stats) merge(o *stats) {
+	for i := range s.phases {
+		s.phases[i].merge(&o.phases[i])
+	}
+	for k, v := range o.status {
+		s.status[k] += v
+	}
+	for k, v := range o.errors {
+		s.errors[k] += v
+	}
+	s.requests += o.requests
+	s.bytes += o.bytes
+	s.newConns += o.newConns
+	s.reused += o.reused
+}
+
+// A result is the outcome of a run.
+type result struct {
+	*stats
+	elapsed time.Duration
+}
+
+// newClient returns the client used for a run of cfg.
+func newClient(cfg *config) *http.Client {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConnsPerHost = cfg.Concurrency
+	t.DisableKeepAlives = cfg.DisableKeepAlives
+	t.ForceAttemptHTTP2 = cfg.HTTP2
+	if !cfg.HTTP2 {
+		t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	if cfg.Insecure {
+		t.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &http.Client{
+		Transport: t,
+		Timeout:   cfg.Timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// run runs the benchmark described by cfg with client c, until the
+// request limit or duration is reached or ctx is done.
+func run(ctx context.Context, cfg *config, c *http.Client) *result {
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	// Workers receive the time at which each request is scheduled. At
+	// a fixed rate, latency is measured from that time rather than
+	// from when a worker becomes free, so time spent queued behind slow
+	// requests counts toward latency.
+	sched := make(chan time.Time)
+	go func() {
+		defer close(sched)
+		var tick <-chan time.Time
+		if cfg.Rate > 0 {
+			t := time.NewTicker(time.Duration(float64(time.Second) / cfg.Rate))
+			defer t.Stop()
+			tick = t.C
+		}
+		for i := 0; cfg.Requests == 0 || i < cfg.Requests; i++ {
+			at := time.Now()
+			if tick != nil && i > 0 {
+				select {
+				case at = <-tick:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case sched <- at:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	all := newStats()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for range max(cfg.Concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := newStats()
+			for at := range sched {
+				do(ctx, cfg, c, at, s)
+			}
+			mu.Lock()
+			all.merge(s)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	c.CloseIdleConnections()
+	return &result{stats: all, elapsed: time.Since(start)}
+}
+
+// A reqTrace records the times of the events of one request. Its
+// hooks may be called from the transport's dialing goroutines.
+type reqTrace struct {
+	mu                                              sync.Mutex
+	dnsStart, connStart, tlsStart, wrote, firstByte time.Time
+	phases                                          [numPhases]time.Duration
+	gotConn, reused                                 bool
+}
+
+func (t *reqTrace) clientTrace() *httptrace.ClientTrace {
+	locked := func(f func()) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		f()
+	}
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { locked(func() { t.dnsStart = time.Now() }) },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			locked(func() { t.phases[phaseDNS] = time.Since(t.dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			locked(func() {
+				if t.connStart.IsZero() {
+					t.connStart = time.Now()
+				}
+			})
+		},
+		ConnectDone: func(string, string, error) {
+			locked(func() { t.phases[phaseConnect] = time.Since(t.connStart) })
+		},
+		TLSHandshakeStart: func() { locked(func() { t.tlsStart = time.Now() }) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			locked(func() { t.phases[phaseTLS] = time.Since(t.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			locked(func() { t.gotConn, t.reused = true, info.Reused })
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { locked(func() { t.wrote = time.Now() }) },
+		GotFirstResponseByte: func() { locked(func() { t.firstByte = time.Now() }) },
+	}
+}
+
+// do makes one request scheduled at time at, recording it in s.
+func do(ctx context.Context, cfg *config, c *http.Client, at time.Time, s *stats) {
+	t := new(reqTrace)
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, t.clientTrace()), cfg.Method, cfg.URL, bytes.NewReader(cfg.Body))
+	if err != nil {
+		s.errors[err.Error()]++
+		return
+	}
+	for k, v := range cfg.Header {
+		req.Header[k] = v
+	}
+	if h := cfg.Header.Get("Host"); h != "" {
+		req.Host = h
+	}
+	if len(cfg.Body) == 0 {
+		req.Body = nil
+		req.ContentLength = 0
+	}
+
+	res, err := c.Do(req)
+	var n int64
+	if err == nil {
+		n, err = io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+	}
+	end := time.Now()
+	if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		// Interrupted by the end of the run; don't count it.
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s.requests++
+	s.bytes += n
+	if t.gotConn {
+		if t.reused {
+			s.reused++
+		} else {
+			s.newConns++
+		}
+	}
+	if err != nil {
+		s.errors[errorKey(err)]++
+		return
+	}
+	s.status[res.StatusCode]++
+	if !t.dnsStart.IsZero() {
+		s.phases[phaseDNS].record(t.phases[phaseDNS])
+	}
+	if !t.connStart.IsZero() {
+		s.phases[phaseConnect].record(t.phases[phaseConnect])
+	}
+	if !t.tlsStart.IsZero() {
+		s.phases[phaseTLS].record(t.phases[phaseTLS])
+	}
+	if !t.wrote.IsZero() && !t.firstByte.IsZero() {
+		s.phases[phaseTTFB].record(t.firstByte.Sub(t.wrote))
+		s.phases[phaseTransfer].record(end.Sub(t.firstByte))
+	}
+	s.phases[phaseTotal].record(end.Sub(at))
+}
+
+// errorKey returns the text by which err is counted, without the
+// request URL included by the client, which is the same for every
+// request.
+func errorKey(err error) string {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		return ue.Err.Error()
+	}
+	return err.Error()
+}
@@ -0,0 +1,126 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnsiilver/http/httptest"
+)
+
+func TestRun(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	}))
+	defer ts.Close()
+
+	cfg := &config{
+		URL:         ts.URL,
+		Method:      "POST",
+		Body:        []byte("ping"),
+		Concurrency: 4,
+		Requests:    100,
+		Timeout:     5 * time.Second,
+	}
+	r := run(context.Background(), cfg, newClient(cfg))
+	if r.requests != 100 || r.status[200] != 100 || len(r.errors) != 0 {
+		t.Fatalf("requests=%d status=%v errors=%v; want 100 requests with status 200", r.requests, r.status, r.errors)
+	}
+	if r.bytes != 400 {
+		t.Errorf("bytes = %d; want 400", r.bytes)
+	}
+	if r.newConns > 4 || r.newConns+r.reused != 100 {
+		t.Errorf("new=%d reused=%d; want at most 4 new of 100", r.newConns, r.reused)
+	}
+	for _, p := range []int{phaseConnect, phaseTTFB, phaseTransfer, phaseTotal} {
+		if h := &r.phases[p]; h.n == 0 {
+			t.Errorf("no %s latencies recorded", phaseNames[p])
+		}
+	}
+	if n := r.phases[phaseTotal].n; n != 100 {
+		t.Errorf("recorded %d total latencies; want 100", n)
+	}
+
+	var buf bytes.Buffer
+	newReport(cfg, r).writeText(&buf)
+	for _, want := range []string{"POST " + ts.URL, "100 in", "  200  100", "reuse", "  total"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("report does not contain %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestRunRate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	cfg := &config{
+		URL:         ts.URL,
+		Method:      "GET",
+		Concurrency: 2,
+		Rate:        100,
+		Requests:    10,
+	}
+	r := run(context.Background(), cfg, newClient(cfg))
+	if r.requests != 10 {
+		t.Fatalf("requests = %d; want 10", r.requests)
+	}
+	// Ten requests at 100/s take at least 90ms.
+	if r.elapsed < 90*time.Millisecond {
+		t.Errorf("elapsed %v; want at least 90ms at the given rate", r.elapsed)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := ts.URL
+	ts.Close()
+	cfg := &config{URL: url, Method: "GET", Concurrency: 1, Requests: 3}
+	r := run(context.Background(), cfg, newClient(cfg))
+	var errs int64
+	for _, n := range r.errors {
+		errs += n
+	}
+	if r.requests != 3 || errs != 3 || len(r.errors) != 1 {
+		t.Fatalf("requests=%d errors=%v; want 3 requests failing with one error", r.requests, r.errors)
+	}
+
+	var buf bytes.Buffer
+	if err := newReport(cfg, r).writeJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var rep report
+	if err := json.Unmarshal(buf.Bytes(), &rep); err != nil {
+		t.Fatal(err)
+	}
+	if rep.Errors != 3 || rep.Requests != 3 {
+		t.Errorf("JSON report has %d errors of %d requests; want 3 of 3", rep.Errors, rep.Requests)
+	}
+}
+
+func TestRunDuration(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	cfg := &config{URL: ts.URL, Method: "GET", Concurrency: 2, Duration: 50 * time.Millisecond}
+	start := time.Now()
+	r := run(context.Background(), cfg, newClient(cfg))
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("run took %v; want about 50ms", d)
+	}
+	if r.requests == 0 || len(r.errors) != 0 {
+		t.Errorf("requests=%d errors=%v; want some requests and no errors", r.requests, r.errors)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// subBits is the number of significant bits kept by a histogram:
+// recorded values are accurate to within 1 part in 1<<(subBits-1).
+const subBits = 8
+
+// A histogram records durations in buckets of bounded relative
+// error, in the manner of an HDR histogram: values below 1<<subBits
+// nanoseconds have a bucket each, and every larger power of two is
+// split into 1<<(subBits-1) equal buckets. Recording is constant-time
+// and the memory used grows only with the logarithm of the largest
+// value.
+type histogram struct {
+	counts   []int64
+	n        int64
+	sum      time.Duration
+	min, max time.Duration
+}
+
+// bucket returns the index of the bucket holding v, which must not be
+// negative.
+func bucket(v int64) int {
+	if v < 1<<subBits {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - subBits
+	m := int(v >> shift) // in [1<<(subBits-1), 1<<subBits)
+	return 1<<subBits + (shift-1)<<(subBits-1) + m - 1<<(subBits-1)
+}
+
+// bucketMax returns the largest value held by bucket i.
+func bucketMax(i int) int64 {
+	if i < 1<<subBits {
+		return int64(i)
+	}
+	j := i - 1<<subBits
+	shift := j>>(subBits-1) + 1
+	m := int64(j&(1<<(subBits-1)-1) + 1<<(subBits-1))
+	return (m+1)<<shift - 1
+}
+
+func (h *histogram) record(d time.Duration) {
+	d = max(d, 0)
+	i := bucket(int64(d))
+	if i >= len(h.counts) {
+		h.counts = append(h.counts, make([]int64, i+1-len(h.counts))...)
+	}
+	h.counts[i]++
+	if h.n == 0 || d < h.min {
+		h.min = d
+	}
+	h.max = max(h.max, d)
+	h.n++
+	h.sum += d
+}
+
+// merge adds the values recorded by o to h.
+func (h *histogram) merge(o *histogram) {
+	if o.n == 0 {
+		return
+	}
+	if len(o.counts) > len(h.counts) {
+		h.counts = append(h.counts, make([]int64, len(o.counts)-len(h.counts))...)
+	}
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	if h.n == 0 || o.min < h.min {
+		h.min = o.min
+	}
+	h.max = max(h.max, o.max)
+	h.n += o.n
+	h.sum += o.sum
+}
+
+func (h *histogram) mean() time.Duration {
+	if h.n == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.n)
+}
+
+// percentile returns the value below or at which p percent of the
+// recorded values lie, rounded up to the bucket's largest value.
+func (h *histogram) percentile(p float64) time.Duration {
+	if h.n == 0 {
+		return 0
+	}
+	rank := max(int64(math.Ceil(p/100*float64(h.n))), 1)
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			return min(time.Duration(bucketMax(i)), h.max)
+		}
+	}
+	return h.max
+}
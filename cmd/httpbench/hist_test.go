@@ -0,0 +1,64 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	prev := -1
+	for _, v := range []int64{0, 1, 255, 256, 257, 511, 512, 1000, 1e6, 1e9, 1e12, 1 << 62} {
+		i := bucket(v)
+		if i < prev {
+			t.Errorf("bucket(%d) = %d, less than bucket of a smaller value", v, i)
+		}
+		prev = i
+		if hi := bucketMax(i); hi < v {
+			t.Errorf("bucketMax(bucket(%d)) = %d; want >= %d", v, hi, v)
+		} else if float64(hi-v) > float64(v)/(1<<(subBits-1)) {
+			t.Errorf("bucketMax(bucket(%d)) = %d; relative error too large", v, hi)
+		}
+		if i > 0 && bucketMax(i-1) >= v {
+			t.Errorf("bucketMax(bucket(%d)-1) = %d; want < %d", v, bucketMax(i-1), v)
+		}
+	}
+}
+
+func TestHistogramPercentile(t *testing.T) {
+	var h histogram
+	for i := 1; i <= 1000; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	for _, tt := range []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0, time.Millisecond},
+		{50, 500 * time.Millisecond},
+		{90, 900 * time.Millisecond},
+		{99.9, 999 * time.Millisecond},
+		{100, 1000 * time.Millisecond},
+	} {
+		got := h.percentile(tt.p)
+		if got < tt.want || float64(got-tt.want) > float64(tt.want)/100 {
+			t.Errorf("percentile(%g) = %v; want %v within 1%%", tt.p, got, tt.want)
+		}
+	}
+	if got, want := h.mean(), 500500*time.Microsecond; got != want {
+		t.Errorf("mean = %v; want %v", got, want)
+	}
+
+	var merged histogram
+	merged.merge(&h)
+	merged.merge(&histogram{})
+	var small histogram
+	small.record(time.Microsecond)
+	merged.merge(&small)
+	if merged.n != 1001 || merged.min != time.Microsecond || merged.max != time.Second {
+		t.Errorf("merged: n=%d min=%v max=%v; want 1001, 1µs, 1s", merged.n, merged.min, merged.max)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Httpbench sends HTTP requests to a URL and reports how quickly they
+// were answered.
+//
+// Usage:
+//
+//	httpbench [flags] url
+//
+// By default httpbench runs -c workers, each sending a request as soon
+// as its previous one completes. With -rate, requests are instead
+// started at a fixed rate, and each request's latency is measured from
+// when it was due to start, so that queueing behind slow requests is
+// not hidden. The run ends after -n requests or -d time, whichever
+// comes first, or on interrupt.
+//
+// The report gives the latency of each phase of the requests, as
+// observed with [httptrace.ClientTrace]: DNS lookup, connecting, the
+// TLS handshake, time to first response byte and reading the body,
+// together with the total. For each it gives the mean and percentiles,
+// accurate to within 1%. It also counts responses by status code,
+// errors, and how often connections were reused.
+//
+// The -local flag runs the benchmark against an in-process test server
+// instead of url, which is useful for checking the client and the
+// command itself, for example in CI.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/johnsiilver/http/httptest"
+)
+
+// headerFlag collects repeated -H flags.
+type headerFlag http.Header
+
+func (h headerFlag) String() string { return "" }
+
+func (h headerFlag) Set(s string) error {
+	k, v, ok := strings.Cut(s, ":")
+	if !ok {
+		return fmt.Errorf("header %q is not of the form Name: value", s)
+	}
+	http.Header(h).Add(strings.TrimSpace(k), strings.TrimSpace(v))
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("httpbench: ")
+
+	cfg := &config{Header: make(http.Header)}
+	flag.IntVar(&cfg.Concurrency, "c", 10, "number of concurrent workers")
+	flag.Float64Var(&cfg.Rate, "rate", 0, "start requests at this many per second, rather than as fast as possible")
+	flag.IntVar(&cfg.Requests, "n", 0, "stop after this many requests (0 for no limit)")
+	flag.DurationVar(&cfg.Duration, "d", 10*time.Second, "stop after this long (0 for no limit)")
+	flag.StringVar(&cfg.Method, "m", "GET", "request method")
+	body := flag.String("body", "", "request body; @file reads it from file")
+	flag.Var(headerFlag(cfg.Header), "H", "add a request header `Name: value` (may be repeated)")
+	flag.DurationVar(&cfg.Timeout, "timeout", 30*time.Second, "timeout for each request")
+	flag.BoolVar(&cfg.Insecure, "k", false, "do not verify the server's TLS certificate")
+	flag.BoolVar(&cfg.DisableKeepAlives, "disable-keepalives", false, "use a new connection for each request")
+	flag.BoolVar(&cfg.HTTP2, "http2", false, "use HTTP/2 when the server supports it")
+	local := flag.Bool("local", false, "benchmark an in-process test server instead of url")
+	jsonOut := flag.Bool("json", false, "print the report as JSON")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: httpbench [flags] url\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	switch {
+	case *local && flag.NArg() == 0:
+		ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+			io.WriteString(w, "hello, world\n")
+		}))
+		ts.EnableHTTP2 = cfg.HTTP2
+		ts.Start()
+		defer ts.Close()
+		cfg.URL = ts.URL
+	case !*local && flag.NArg() == 1:
+		cfg.URL = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if cfg.Requests == 0 && cfg.Duration == 0 {
+		log.Fatal("one of -n and -d must be set")
+	}
+	if cfg.Concurrency < 1 {
+		log.Fatal("-c must be at least 1")
+	}
+	if name, ok := strings.CutPrefix(*body, "@"); ok {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg.Body = b
+	} else {
+		cfg.Body = []byte(*body)
+	}
+	if _, err := http.NewRequest(cfg.Method, cfg.URL, nil); err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	rep := newReport(cfg, run(ctx, cfg, newClient(cfg)))
+	var err error
+	if *jsonOut {
+		err = rep.writeJSON(os.Stdout)
+	} else {
+		err = rep.writeText(os.Stdout)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
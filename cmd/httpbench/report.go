@@ -0,0 +1,149 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// percentiles are the percentiles reported for each phase.
+var percentiles = []float64{50, 90, 99, 99.9}
+
+// A report is the summary of a run, as printed in JSON.
+type report struct {
+	Method        string           `json:"method"`
+	URL           string           `json:"url"`
+	Concurrency   int              `json:"concurrency"`
+	Rate          float64          `json:"rate,omitempty"`
+	Elapsed       float64          `json:"elapsed_seconds"`
+	Requests      int64            `json:"requests"`
+	Errors        int64            `json:"errors"`
+	Throughput    float64          `json:"requests_per_second"`
+	Bytes         int64            `json:"bytes"`
+	NewConns      int64            `json:"new_connections"`
+	ReusedConns   int64            `json:"reused_connections"`
+	ReuseRate     float64          `json:"connection_reuse_rate"`
+	Status        map[int]int64    `json:"status"`
+	ErrorMessages map[string]int64 `json:"error_messages,omitempty"`
+	Latency       map[string]phase `json:"latency"`
+}
+
+// A phase summarizes the latency of one phase of the requests, in
+// milliseconds.
+type phase struct {
+	Count       int64              `json:"count"`
+	Min         float64            `json:"min_ms"`
+	Mean        float64            `json:"mean_ms"`
+	Max         float64            `json:"max_ms"`
+	Percentiles map[string]float64 `json:"percentiles_ms"`
+}
+
+func ms(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+
+func newReport(cfg *config, r *result) *report {
+	rep := &report{
+		Method:        cfg.Method,
+		URL:           cfg.URL,
+		Concurrency:   cfg.Concurrency,
+		Rate:          cfg.Rate,
+		Elapsed:       r.elapsed.Seconds(),
+		Requests:      r.requests,
+		Bytes:         r.bytes,
+		NewConns:      r.newConns,
+		ReusedConns:   r.reused,
+		Status:        r.status,
+		ErrorMessages: r.errors,
+		Latency:       make(map[string]phase),
+	}
+	for _, n := range r.errors {
+		rep.Errors += n
+	}
+	if r.elapsed > 0 {
+		rep.Throughput = float64(r.requests) / r.elapsed.Seconds()
+	}
+	if conns := r.newConns + r.reused; conns > 0 {
+		rep.ReuseRate = float64(r.reused) / float64(conns)
+	}
+	for i, name := range phaseNames {
+		h := &r.phases[i]
+		if h.n == 0 {
+			continue
+		}
+		p := phase{
+			Count:       h.n,
+			Min:         ms(h.min),
+			Mean:        ms(h.mean()),
+			Max:         ms(h.max),
+			Percentiles: make(map[string]float64),
+		}
+		for _, q := range percentiles {
+			p.Percentiles[fmt.Sprintf("p%g", q)] = ms(h.percentile(q))
+		}
+		rep.Latency[name] = p
+	}
+	return rep
+}
+
+func (rep *report) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
+
+func (rep *report) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "Target:\t%s %s\n", rep.Method, rep.URL)
+	if rep.Rate > 0 {
+		fmt.Fprintf(tw, "Load:\t%d workers at %g req/s\n", rep.Concurrency, rep.Rate)
+	} else {
+		fmt.Fprintf(tw, "Load:\t%d workers\n", rep.Concurrency)
+	}
+	fmt.Fprintf(tw, "Requests:\t%d in %.3fs, %.1f req/s, %d errors\n",
+		rep.Requests, rep.Elapsed, rep.Throughput, rep.Errors)
+	fmt.Fprintf(tw, "Received:\t%d bytes\n", rep.Bytes)
+	fmt.Fprintf(tw, "Connections:\t%d new, %d reused (%.1f%% reuse)\n",
+		rep.NewConns, rep.ReusedConns, 100*rep.ReuseRate)
+	tw.Flush()
+
+	if len(rep.Status) > 0 {
+		fmt.Fprintf(w, "\nStatus codes:\n")
+		for _, code := range slices.Sorted(maps.Keys(rep.Status)) {
+			fmt.Fprintf(tw, "  %d\t%d\n", code, rep.Status[code])
+		}
+		tw.Flush()
+	}
+	if len(rep.ErrorMessages) > 0 {
+		fmt.Fprintf(w, "\nErrors:\n")
+		for _, msg := range slices.Sorted(maps.Keys(rep.ErrorMessages)) {
+			fmt.Fprintf(tw, "  %d\t%s\n", rep.ErrorMessages[msg], msg)
+		}
+		tw.Flush()
+	}
+
+	fmt.Fprintf(w, "\nLatency (ms):\n")
+	fmt.Fprintf(tw, "  phase\tcount\tmin\tmean")
+	for _, q := range percentiles {
+		fmt.Fprintf(tw, "\tp%g", q)
+	}
+	fmt.Fprintf(tw, "\tmax\n")
+	for _, name := range phaseNames {
+		p, ok := rep.Latency[name]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(tw, "  %s\t%d\t%.3f\t%.3f", name, p.Count, p.Min, p.Mean)
+		for _, q := range percentiles {
+			fmt.Fprintf(tw, "\t%.3f", p.Percentiles[fmt.Sprintf("p%g", q)])
+		}
+		fmt.Fprintf(tw, "\t%.3f\n", p.Max)
+	}
+	return tw.Flush()
+}
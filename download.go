@@ -0,0 +1,620 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Resumable downloads.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMinSegmentSize is the default value of
+	// [Downloader.MinSegmentSize].
+	DefaultMinSegmentSize = 1 << 20
+
+	// DefaultDownloadRetries is the default value of
+	// [Downloader.MaxRetries].
+	DefaultDownloadRetries = 5
+
+	// DefaultDownloadRetryDelay is the default value of
+	// [Downloader.RetryDelay].
+	DefaultDownloadRetryDelay = time.Second
+
+	// maxDownloadRetryDelay bounds the delay between retries.
+	maxDownloadRetryDelay = time.Minute
+
+	// downloadSaveInterval is how often the progress of a download is
+	// saved while data arrives.
+	downloadSaveInterval = time.Second
+)
+
+// ErrChecksum is returned by [Downloader.Download] if the downloaded
+// file does not match the expected checksum.
+var ErrChecksum = errors.New("http: download checksum mismatch")
+
+// A Downloader downloads files with GET requests, resuming them after
+// network errors, server errors and restarts of the program.
+//
+// While a file is downloaded, its data is kept in a partial file named
+// by adding ".part" to its name, and the progress of the download in
+// another file named by adding ".part.json". Download resumes from
+// them if they are left over from an earlier call, asking only for
+// the missing bytes with Range requests. Each such request has an
+// If-Range header with the ETag or Last-Modified time of the first
+// response, so that if the file on the server has changed the
+// download starts over. Files whose responses have neither a strong
+// ETag nor a Last-Modified time are always downloaded from the start.
+//
+// If the server advertises support for ranges with Accept-Ranges:
+// bytes, a large file can be downloaded in several segments in
+// parallel.
+type Downloader struct {
+	// Client is the client making requests. If nil, DefaultClient is
+	// used.
+	Client *Client
+
+	Header Header // optional extra headers for each request
+
+	// Parallel is the largest number of segments downloaded at once.
+	// If it is less than 2, files are downloaded in one piece.
+	Parallel int
+
+	// MinSegmentSize is the size of the smallest segment a file is
+	// split into. If zero, DefaultMinSegmentSize is used.
+	MinSegmentSize int64
+
+	// MaxRetries is the number of times a request is retried after
+	// failing without making progress. If zero,
+	// DefaultDownloadRetries is used; if negative, failed requests are
+	// not retried.
+	MaxRetries int
+
+	// RetryDelay is the delay before the first retry of a request.
+	// It doubles after each failed attempt, up to a minute. If zero,
+	// DefaultDownloadRetryDelay is used.
+	RetryDelay time.Duration
+
+	// If Hash is not nil, the checksum of the downloaded file computed
+	// with it must equal Checksum, or Download removes the file and
+	// returns an error wrapping ErrChecksum.
+	Hash     func() hash.Hash
+	Checksum []byte
+
+	// Progress, if not nil, is called as data is written, with the
+	// number of bytes of the file downloaded so far and its size,
+	// which is -1 if unknown. Calls are not concurrent.
+	Progress func(written, total int64)
+}
+
+func (d *Downloader) client() *Client {
+	if d.Client != nil {
+		return d.Client
+	}
+	return DefaultClient
+}
+
+func (d *Downloader) maxRetries() int {
+	switch {
+	case d.MaxRetries == 0:
+		return DefaultDownloadRetries
+	case d.MaxRetries < 0:
+		return 0
+	}
+	return d.MaxRetries
+}
+
+func (d *Downloader) retryDelay() time.Duration {
+	if d.RetryDelay > 0 {
+		return d.RetryDelay
+	}
+	return DefaultDownloadRetryDelay
+}
+
+// segments returns the number of segments a file of the given size is
+// split into.
+func (d *Downloader) segments(size int64) int64 {
+	if d.Parallel < 2 || size <= 0 {
+		return 1
+	}
+	minSize := d.MinSegmentSize
+	if minSize <= 0 {
+		minSize = DefaultMinSegmentSize
+	}
+	return max(1, min(int64(d.Parallel), size/minSize))
+}
+
+// Download downloads the resource at url to the file name, replacing
+// it, and resuming the download from an earlier call if possible. On
+// error, the partial file is kept so that a later call can resume it,
+// unless the checksum of the complete file is wrong.
+func (d *Downloader) Download(ctx context.Context, url, name string) error {
+	part := name + ".part"
+	f, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dl := &download{d: d, url: url, f: f, statePath: part + ".json"}
+
+	resume := dl.load()
+	for restarts := 0; ; restarts++ {
+		err = dl.run(ctx, resume)
+		if !errors.Is(err, errRepresentationChanged) || restarts >= d.maxRetries() {
+			break
+		}
+		resume = false
+	}
+	if err != nil {
+		if errors.Is(err, errRepresentationChanged) {
+			err = fmt.Errorf("http: download %s: %w", url, err)
+		}
+		if saveErr := dl.save(); saveErr != nil {
+			return errors.Join(err, saveErr)
+		}
+		return err
+	}
+
+	if d.Hash != nil {
+		h := d.Hash()
+		if _, err := io.Copy(h, io.NewSectionReader(f, 0, dl.state.Size)); err != nil {
+			return err
+		}
+		if sum := h.Sum(nil); !bytes.Equal(sum, d.Checksum) {
+			f.Close()
+			os.Remove(part)
+			os.Remove(dl.statePath)
+			return fmt.Errorf("%w: %s: got %x, want %x", ErrChecksum, url, sum, d.Checksum)
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(part, name); err != nil {
+		return err
+	}
+	if err := os.Remove(dl.statePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// errRepresentationChanged means that the resource changed during the
+// download, which must start over.
+var errRepresentationChanged = errors.New("resource changed during download")
+
+// A downloadStatusError is an unexpected response status.
+type downloadStatusError struct {
+	code   int
+	status string
+}
+
+func (e *downloadStatusError) Error() string { return "unexpected response status " + e.status }
+
+// A downloadWriteError is an error writing the partial file.
+type downloadWriteError struct{ error }
+
+func (e downloadWriteError) Unwrap() error { return e.error }
+
+// retryable reports whether a request failing with err may succeed
+// if tried again.
+func retryable(err error) bool {
+	var se *downloadStatusError
+	switch {
+	case errors.Is(err, errRepresentationChanged),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, new(downloadWriteError)):
+		return false
+	case errors.As(err, &se):
+		return se.code >= 500 || se.code == StatusRequestTimeout || se.code == StatusTooManyRequests
+	}
+	return true
+}
+
+// downloadState is the progress of a download, saved in JSON with the
+// partial file.
+type downloadState struct {
+	URL       string `json:"url"`
+	Validator string `json:"validator"` // ETag or Last-Modified time, for If-Range
+	Size      int64  `json:"size"`      // -1 if unknown
+
+	// Segments are the parts of the file downloaded separately.
+	Segments []downloadSegment `json:"segments"`
+}
+
+type downloadSegment struct {
+	Start   int64 `json:"start"`
+	End     int64 `json:"end"`     // exclusive; -1 if the size is unknown
+	Written int64 `json:"written"` // bytes written from Start
+}
+
+// remaining returns the number of bytes of s still to download, or -1
+// if unknown.
+func (s *downloadSegment) remaining() int64 {
+	if s.End < 0 {
+		return -1
+	}
+	return s.End - s.Start - s.Written
+}
+
+// A download is a single call of Downloader.Download.
+type download struct {
+	d         *Downloader
+	url       string
+	f         *os.File // partial file
+	statePath string
+
+	progressMu sync.Mutex // serializes calls of d.Progress
+
+	mu      sync.Mutex // guards the fields below
+	state   downloadState
+	written int64 // total of state.Segments[i].Written
+	saved   time.Time
+}
+
+// load loads the saved state of an earlier download of dl.url and
+// reports whether the download can resume from it.
+func (dl *download) load() bool {
+	b, err := os.ReadFile(dl.statePath)
+	if err != nil {
+		return false
+	}
+	var st downloadState
+	if err := json.Unmarshal(b, &st); err != nil || st.URL != dl.url || st.Validator == "" || len(st.Segments) == 0 {
+		return false
+	}
+	dl.state = st
+	dl.written = 0
+	for _, s := range st.Segments {
+		dl.written += s.Written
+	}
+	return true
+}
+
+// save saves the state of the download next to the partial file.
+func (dl *download) save() error {
+	dl.mu.Lock()
+	b, err := json.Marshal(dl.state)
+	dl.saved = time.Now()
+	dl.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp := dl.statePath + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dl.statePath)
+}
+
+// run runs the download, from the start unless resume is set.
+func (dl *download) run(ctx context.Context, resume bool) error {
+	var first *Response
+	if !resume {
+		// The saved state no longer describes the partial file.
+		if err := os.Remove(dl.statePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err := dl.f.Truncate(0); err != nil {
+			return err
+		}
+		var err error
+		if first, err = dl.start(ctx); err != nil {
+			return err
+		}
+	}
+	dl.mu.Lock()
+	var todo []int // segments to download
+	for i, s := range dl.state.Segments {
+		if s.remaining() != 0 {
+			todo = append(todo, i)
+		}
+	}
+	written, total := dl.written, dl.state.Size
+	dl.mu.Unlock()
+	dl.progress(written, total)
+	if first != nil && (len(todo) == 0 || todo[0] != 0) {
+		first.Body.Close()
+		first = nil
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	var wg sync.WaitGroup
+	for _, i := range todo {
+		var resp *Response
+		if i == 0 {
+			resp = first
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := dl.fetch(ctx, i, resp); err != nil {
+				cancel(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := context.Cause(ctx); err != nil {
+		return err
+	}
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	if dl.state.Size < 0 {
+		// The size is known now that the whole file has arrived.
+		dl.state.Size = dl.written
+	}
+	return nil
+}
+
+// start makes the first request of a download from the start, and
+// sets up its state from the response.
+func (dl *download) start(ctx context.Context) (*Response, error) {
+	var resp *Response
+	err := dl.retry(ctx, func() (int64, error) {
+		var err error
+		resp, err = dl.get(ctx, nil)
+		if err != nil {
+			return 0, err
+		}
+		if resp.StatusCode != StatusOK {
+			resp.Body.Close()
+			return 0, &downloadStatusError{resp.StatusCode, resp.Status}
+		}
+		return 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	st := downloadState{
+		URL:       dl.url,
+		Validator: validator(resp.Header),
+		Size:      resp.ContentLength,
+	}
+	n := int64(1)
+	if st.Validator != "" && resp.Header.Get("Accept-Ranges") == "bytes" {
+		n = dl.d.segments(st.Size)
+	}
+	if n == 1 {
+		st.Segments = []downloadSegment{{Start: 0, End: st.Size}}
+	} else {
+		segSize := (st.Size + n - 1) / n
+		for start := int64(0); start < st.Size; start += segSize {
+			st.Segments = append(st.Segments, downloadSegment{Start: start, End: min(start+segSize, st.Size)})
+		}
+	}
+	dl.mu.Lock()
+	dl.state = st
+	dl.written = 0
+	dl.mu.Unlock()
+	return resp, nil
+}
+
+// validator returns the value of an If-Range header for the
+// representation described by h: a strong ETag, or the Last-Modified
+// time, or "" if neither is set.
+func validator(h Header) string {
+	if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+		return etag
+	}
+	return h.Get("Last-Modified")
+}
+
+// get sends a GET request for dl.url with the given header fields
+// added.
+func (dl *download) get(ctx context.Context, h Header) (*Response, error) {
+	req, err := NewRequestWithContext(ctx, "GET", dl.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, vv := range dl.d.Header {
+		req.Header[k] = vv
+	}
+	for k, vv := range h {
+		req.Header[k] = vv
+	}
+	// Ask for the bytes as stored, so that ranges and Content-Length
+	// count them, rather than a compressed encoding the Transport would
+	// decompress.
+	req.Header.Set("Accept-Encoding", "identity")
+	return dl.d.client().Do(req)
+}
+
+// fetch downloads the rest of segment i, starting with the response
+// resp if it is not nil.
+func (dl *download) fetch(ctx context.Context, i int, resp *Response) error {
+	return dl.retry(ctx, func() (int64, error) {
+		if resp == nil {
+			var err error
+			if resp, err = dl.getRange(ctx, i); err != nil {
+				return 0, err
+			}
+		}
+		defer func() {
+			resp.Body.Close()
+			resp = nil
+		}()
+		return dl.copy(i, resp.Body)
+	})
+}
+
+// getRange requests the missing bytes of segment i.
+func (dl *download) getRange(ctx context.Context, i int) (*Response, error) {
+	dl.mu.Lock()
+	s := dl.state.Segments[i]
+	validator := dl.state.Validator
+	size := dl.state.Size
+	dl.mu.Unlock()
+	if validator == "" {
+		// Without a validator, the bytes received so far cannot be
+		// combined safely with those of another response.
+		return nil, errRepresentationChanged
+	}
+	start := s.Start + s.Written
+	rng := fmt.Sprintf("bytes=%d-", start)
+	if s.End >= 0 {
+		rng += strconv.FormatInt(s.End-1, 10)
+	}
+	resp, err := dl.get(ctx, Header{"Range": {rng}, "If-Range": {validator}})
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case StatusPartialContent:
+		gotStart, gotSize, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if ok && gotStart == start && (size < 0 || gotSize < 0 || gotSize == size) {
+			return resp, nil
+		}
+		err = errRepresentationChanged
+	case StatusOK, StatusRequestedRangeNotSatisfiable:
+		// If-Range failed, or the resource is shorter than it was.
+		err = errRepresentationChanged
+	default:
+		err = &downloadStatusError{resp.StatusCode, resp.Status}
+	}
+	resp.Body.Close()
+	return nil, err
+}
+
+// parseContentRange parses the value of the Content-Range header of a
+// 206 response with a single part, "bytes first-last/size", returning
+// the first byte and the size, or -1 for a size of "*".
+func parseContentRange(s string) (start, size int64, ok bool) {
+	rng, ok := strings.CutPrefix(s, "bytes ")
+	if !ok {
+		return 0, 0, false
+	}
+	rng, sizeStr, ok := strings.Cut(rng, "/")
+	if !ok {
+		return 0, 0, false
+	}
+	first, _, ok := strings.Cut(rng, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false
+	}
+	if sizeStr == "*" {
+		return start, -1, true
+	}
+	size, err = strconv.ParseInt(sizeStr, 10, 64)
+	if err != nil || size < 0 {
+		return 0, 0, false
+	}
+	return start, size, true
+}
+
+// copy copies the body of a response for the missing bytes of segment
+// i to the partial file, returning the number of bytes written.
+func (dl *download) copy(i int, body io.Reader) (int64, error) {
+	buf := make([]byte, 32<<10)
+	var n int64
+	for {
+		dl.mu.Lock()
+		s := dl.state.Segments[i]
+		dl.mu.Unlock()
+		rem := s.remaining()
+		if rem == 0 {
+			return n, nil
+		}
+		p := buf
+		if rem > 0 && rem < int64(len(p)) {
+			p = p[:rem]
+		}
+		nr, err := body.Read(p)
+		if nr > 0 {
+			if _, werr := dl.f.WriteAt(p[:nr], s.Start+s.Written); werr != nil {
+				return n, downloadWriteError{werr}
+			}
+			n += int64(nr)
+			dl.mu.Lock()
+			dl.state.Segments[i].Written += int64(nr)
+			dl.written += int64(nr)
+			written, total := dl.written, dl.state.Size
+			save := time.Since(dl.saved) >= downloadSaveInterval
+			dl.mu.Unlock()
+			dl.progress(written, total)
+			if save {
+				if err := dl.save(); err != nil {
+					return n, downloadWriteError{err}
+				}
+			}
+		}
+		switch {
+		case int64(nr) == rem:
+			return n, nil
+		case err == io.EOF && rem < 0:
+			// The whole file has arrived.
+			dl.mu.Lock()
+			s := &dl.state.Segments[i]
+			s.End = s.Start + s.Written
+			dl.mu.Unlock()
+			return n, nil
+		case err == io.EOF:
+			return n, io.ErrUnexpectedEOF
+		case err != nil:
+			return n, err
+		}
+	}
+}
+
+func (dl *download) progress(written, total int64) {
+	if dl.d.Progress == nil {
+		return
+	}
+	dl.progressMu.Lock()
+	defer dl.progressMu.Unlock()
+	dl.d.Progress(written, total)
+}
+
+// retry calls f until it succeeds, fails with an error that is not
+// retryable, or fails MaxRetries times in a row without making
+// progress. f returns the number of bytes it downloaded.
+func (dl *download) retry(ctx context.Context, f func() (int64, error)) error {
+	delay := dl.d.retryDelay()
+	failures := 0
+	for {
+		n, err := f()
+		switch {
+		case err == nil:
+			return nil
+		case ctx.Err() != nil:
+			return context.Cause(ctx)
+		case !retryable(err):
+			return err
+		}
+		if n > 0 {
+			failures = 0
+			delay = dl.d.retryDelay()
+		}
+		if failures >= dl.d.maxRetries() {
+			return err
+		}
+		failures++
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return context.Cause(ctx)
+		case <-t.C:
+		}
+		delay = min(2*delay, maxDownloadRetryDelay)
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/johnsiilver/http/httptest"
+)
+
+var downloadContent = bytes.Repeat([]byte("0123456789abcdef"), 4096) // 64 KiB
+
+// A downloadServer serves downloadContent, recording the Range headers
+// of the requests, and can cut off responses.
+type downloadServer struct {
+	etag    string
+	modtime time.Time
+
+	mu     sync.Mutex
+	ranges []string
+	cut    int // number of responses still to cut off
+}
+
+func (s *downloadServer) ServeHTTP(w ResponseWriter, r *Request) {
+	s.mu.Lock()
+	s.ranges = append(s.ranges, r.Header.Get("Range"))
+	cut := s.cut > 0
+	if cut {
+		s.cut--
+	}
+	s.mu.Unlock()
+	if s.etag != "" {
+		w.Header().Set("ETag", s.etag)
+	}
+	if cut {
+		w = &cutWriter{ResponseWriter: w, n: 1000}
+	}
+	ServeContent(w, r, "", s.modtime, bytes.NewReader(downloadContent))
+}
+
+func (s *downloadServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ranges...)
+}
+
+// A cutWriter aborts the response after n bytes of the body.
+type cutWriter struct {
+	ResponseWriter
+	n int
+}
+
+func (w *cutWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		w.ResponseWriter.Write(p[:w.n])
+		NewResponseController(w.ResponseWriter).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	w.n -= len(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func newDownloadServer(t *testing.T, s *downloadServer) string {
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func checkDownloaded(t *testing.T, name string) {
+	t.Helper()
+	got, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, downloadContent) {
+		t.Errorf("downloaded %d bytes, differing from the %d bytes served", len(got), len(downloadContent))
+	}
+	for _, ext := range []string{".part", ".part.json"} {
+		if _, err := os.Stat(name + ext); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s left behind: %v", ext, err)
+		}
+	}
+}
+
+func TestDownload(t *testing.T) {
+	s := &downloadServer{etag: `"v1"`}
+	url := newDownloadServer(t, s)
+	name := filepath.Join(t.TempDir(), "file")
+	sum := sha256.Sum256(downloadContent)
+	var last, total int64
+	d := &Downloader{
+		Hash:     sha256.New,
+		Checksum: sum[:],
+		Progress: func(written, size int64) {
+			if written < last {
+				t.Errorf("progress went back from %d to %d", last, written)
+			}
+			last, total = written, size
+		},
+	}
+	if err := d.Download(context.Background(), url, name); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, name)
+	if want := int64(len(downloadContent)); last != want || total != want {
+		t.Errorf("last progress = %d/%d; want %d/%d", last, total, want, want)
+	}
+	if got := s.requests(); len(got) != 1 || got[0] != "" {
+		t.Errorf("requests with Range headers %q; want one without", got)
+	}
+}
+
+func TestDownloadParallel(t *testing.T) {
+	s := &downloadServer{etag: `"v1"`}
+	url := newDownloadServer(t, s)
+	name := filepath.Join(t.TempDir(), "file")
+	d := &Downloader{Parallel: 4, MinSegmentSize: 10000}
+	if err := d.Download(context.Background(), url, name); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, name)
+	got := s.requests()
+	want := []string{"", "bytes=16384-32767", "bytes=32768-49151", "bytes=49152-65535"}
+	if len(got) != len(want) || got[0] != "" {
+		t.Fatalf("requests with Range headers %q; want %q in any order after the first", got, want)
+	}
+	for _, w := range want[1:] {
+		if !strings.Contains(strings.Join(got, ","), w) {
+			t.Errorf("requests with Range headers %q; missing %q", got, w)
+		}
+	}
+}
+
+func TestDownloadRetry(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		etag      string
+		modtime   time.Time
+		wantRange string
+	}{
+		{name: "etag", etag: `"v1"`, wantRange: "bytes=1000-65535"},
+		{name: "last-modified", modtime: time.Unix(1e9, 0), wantRange: "bytes=1000-65535"},
+		{name: "no validator", wantRange: ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &downloadServer{etag: tt.etag, modtime: tt.modtime, cut: 1}
+			url := newDownloadServer(t, s)
+			name := filepath.Join(t.TempDir(), "file")
+			d := &Downloader{RetryDelay: time.Millisecond}
+			if err := d.Download(context.Background(), url, name); err != nil {
+				t.Fatal(err)
+			}
+			checkDownloaded(t, name)
+			if got := s.requests(); len(got) != 2 || got[1] != tt.wantRange {
+				t.Errorf("requests with Range headers %q; want a retry with %q", got, tt.wantRange)
+			}
+		})
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	s := &downloadServer{etag: `"v1"`, cut: 1}
+	url := newDownloadServer(t, s)
+	name := filepath.Join(t.TempDir(), "file")
+	d := &Downloader{MaxRetries: -1}
+	if err := d.Download(context.Background(), url, name); err == nil {
+		t.Fatal("Download of a cut off response succeeded")
+	}
+	b, err := os.ReadFile(name + ".part.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var st downloadState
+	if err := json.Unmarshal(b, &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Validator != `"v1"` || len(st.Segments) != 1 || st.Segments[0].Written != 1000 {
+		t.Fatalf("saved state = %+v; want 1000 bytes written of a file with ETag \"v1\"", st)
+	}
+
+	if err := d.Download(context.Background(), url, name); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, name)
+	if got := s.requests(); len(got) != 2 || got[1] != "bytes=1000-65535" {
+		t.Errorf("requests with Range headers %q; want a resumed request for bytes=1000-65535", got)
+	}
+}
+
+func TestDownloadChanged(t *testing.T) {
+	s := &downloadServer{etag: `"v1"`, cut: 1}
+	url := newDownloadServer(t, s)
+	name := filepath.Join(t.TempDir(), "file")
+	d := &Downloader{MaxRetries: -1}
+	if err := d.Download(context.Background(), url, name); err == nil {
+		t.Fatal("Download of a cut off response succeeded")
+	}
+	s.mu.Lock()
+	s.etag = `"v2"`
+	s.mu.Unlock()
+	d.MaxRetries = 1
+	if err := d.Download(context.Background(), url, name); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, name)
+	if got := s.requests(); len(got) != 3 || got[1] != "bytes=1000-65535" || got[2] != "" {
+		t.Errorf("requests with Range headers %q; want a failed resume and a new download", got)
+	}
+}
+
+func TestDownloadChecksum(t *testing.T) {
+	url := newDownloadServer(t, &downloadServer{etag: `"v1"`})
+	name := filepath.Join(t.TempDir(), "file")
+	d := &Downloader{Hash: sha256.New, Checksum: make([]byte, sha256.Size)}
+	if err := d.Download(context.Background(), url, name); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("Download error = %v; want ErrChecksum", err)
+	}
+	for _, n := range []string{name, name + ".part", name + ".part.json"} {
+		if _, err := os.Stat(n); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s exists after checksum mismatch: %v", n, err)
+		}
+	}
+}
+
+func TestDownloadStatus(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		calls++
+		Error(w, "gone", StatusNotFound)
+	}))
+	defer ts.Close()
+	d := &Downloader{RetryDelay: time.Millisecond}
+	err := d.Download(context.Background(), ts.URL, filepath.Join(t.TempDir(), "file"))
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Download error = %v; want a 404 status error", err)
+	}
+	if calls != 1 {
+		t.Errorf("%d requests; want 1, without retries", calls)
+	}
+}
@@ -0,0 +1,391 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 103 (Early Hints) responses, RFC 8297.
+
+package http
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/http/httpguts"
+)
+
+// A Link is a value of a Link header, RFC 8288, such as those sent in
+// 103 (Early Hints) responses to let the client start fetching the
+// resources a page needs while the server prepares it.
+type Link struct {
+	URL string // target URI reference, relative to the request URL
+	Rel string // relation type; "preload" if empty
+
+	// As is the destination of a preloaded resource, such as
+	// "style", "script", "font" or "image".
+	As string
+
+	Type string // media type of the resource, if known
+
+	// CrossOrigin is the CORS mode of the request for the resource:
+	// "anonymous" or "use-credentials", or empty for none.
+	CrossOrigin string
+}
+
+// String returns the Link header value for l, such as
+// `</style.css>; rel=preload; as=style`.
+func (l Link) String() string {
+	var b strings.Builder
+	b.WriteString("<" + l.URL + ">; rel=")
+	if l.Rel != "" {
+		b.WriteString(quoteLinkParam(l.Rel))
+	} else {
+		b.WriteString("preload")
+	}
+	if l.As != "" {
+		b.WriteString("; as=" + quoteLinkParam(l.As))
+	}
+	if l.Type != "" {
+		b.WriteString("; type=" + quoteLinkParam(l.Type))
+	}
+	switch l.CrossOrigin {
+	case "":
+	case "anonymous":
+		b.WriteString("; crossorigin")
+	default:
+		b.WriteString("; crossorigin=" + quoteLinkParam(l.CrossOrigin))
+	}
+	return b.String()
+}
+
+// quoteLinkParam returns v, quoted unless it is a token.
+func quoteLinkParam(v string) string {
+	if v != "" && strings.IndexFunc(v, func(r rune) bool { return !httpguts.IsTokenRune(r) }) < 0 {
+		return v
+	}
+	return fmt.Sprintf("%q", v)
+}
+
+// WriteEarlyHints sends a 103 (Early Hints) response on w with a Link
+// header for each of links, and reports whether it was sent. It must
+// be called before the final response is written, and may be called
+// more than once.
+//
+// Early hints replace HTTP/2 server push, which most browsers no
+// longer support: a client can start fetching the linked resources,
+// or connecting to their servers, while the server prepares the
+// final response. Hints are sent over HTTP/1.1 and HTTP/2.
+//
+// WriteEarlyHints does nothing and reports false if there are no
+// links, if the request r is HTTP/1.0, whose clients do not expect
+// interim responses, or if w is not known to support them. Writers
+// are known to support them if they, or a writer they wrap as
+// reported by Unwrap() ResponseWriter, the convention of
+// [ResponseController], implement [InterimWriter] and say so, or if r
+// was received by a [Server], whose ResponseWriters send interim
+// responses over HTTP/1.1 and HTTP/2. Handlers wrapping the
+// ResponseWriter of a Server must pass 1xx status codes on to it.
+//
+// The Link headers are not left in w.Header(): they are not part of
+// the final response unless the handler adds them.
+func WriteEarlyHints(w ResponseWriter, r *Request, links ...Link) bool {
+	if w == nil || len(links) == 0 || !r.ProtoAtLeast(1, 1) || !canWriteInterim(w, r) {
+		return false
+	}
+	h := w.Header()
+	saved, hadLink := h["Link"]
+	vv := make([]string, len(links))
+	for i, l := range links {
+		vv[i] = l.String()
+	}
+	h["Link"] = vv
+	w.WriteHeader(StatusEarlyHints)
+	if hadLink {
+		h["Link"] = saved
+	} else {
+		delete(h, "Link")
+	}
+	return true
+}
+
+// An InterimWriter is a ResponseWriter that can say whether it sends
+// 1xx (informational) status codes passed to WriteHeader as interim
+// responses, followed by the final response, rather than taking them
+// as the final status. The recorders of package httptest implement
+// it.
+type InterimWriter interface {
+	ResponseWriter
+
+	// WritesInterimResponses reports whether the writer sends
+	// interim responses.
+	WritesInterimResponses() bool
+}
+
+// canWriteInterim reports whether w, writing the response to r, is
+// known to send interim responses.
+func canWriteInterim(w ResponseWriter, r *Request) bool {
+	for w != nil {
+		if iw, ok := w.(InterimWriter); ok {
+			return iw.WritesInterimResponses()
+		}
+		u, ok := w.(interface{ Unwrap() ResponseWriter })
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	_, ok := r.Context().Value(ServerContextKey).(*Server)
+	return ok
+}
+
+const (
+	// maxEarlyHintRoutes bounds the number of paths for which an
+	// EarlyHintsHandler remembers links.
+	maxEarlyHintRoutes = 1024
+
+	// maxEarlyHintLinks bounds the number of links sent in the hints
+	// for a path.
+	maxEarlyHintLinks = 16
+
+	// earlyHintsScanSize is the length of the start of an HTML
+	// response scanned for links.
+	earlyHintsScanSize = 64 << 10
+)
+
+// EarlyHintsHandler returns a handler that runs h, sending a 103 (Early
+// Hints) response before each GET or HEAD request with the links that
+// h's last successful response for the same path had.
+//
+// Routes are learned by path alone, whatever the Host of the request,
+// so that clients cannot crowd out the paths of a site with requests
+// for made-up hosts. A handler serving several sites with differing
+// content should wrap the handler of each site separately.
+//
+// The links are learned from the Link headers of h's responses with
+// the relation types preload, modulepreload and preconnect, and from
+// the head of HTML responses: stylesheets, scripts and preloaded
+// resources. Hints are sent as by [WriteEarlyHints], and so not at
+// all when the ResponseWriter cannot send them.
+func EarlyHintsHandler(h Handler) Handler {
+	return &earlyHintsHandler{h: h, routes: make(map[string][]Link)}
+}
+
+type earlyHintsHandler struct {
+	h Handler
+
+	mu     sync.Mutex
+	routes map[string][]Link // by path
+}
+
+func (eh *earlyHintsHandler) ServeHTTP(w ResponseWriter, r *Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		eh.h.ServeHTTP(w, r)
+		return
+	}
+	key := r.URL.Path
+	eh.mu.Lock()
+	links := eh.routes[key]
+	eh.mu.Unlock()
+	WriteEarlyHints(w, r, links...)
+
+	ew := &earlyHintsWriter{ResponseWriter: w}
+	eh.h.ServeHTTP(ew, r)
+	if ew.code < 200 || ew.code > 299 {
+		return
+	}
+	links = ew.links()
+	eh.mu.Lock()
+	defer eh.mu.Unlock()
+	if len(links) == 0 {
+		delete(eh.routes, key)
+		return
+	}
+	if _, ok := eh.routes[key]; !ok && len(eh.routes) >= maxEarlyHintRoutes {
+		for k := range eh.routes {
+			delete(eh.routes, k)
+			break
+		}
+	}
+	eh.routes[key] = links
+}
+
+// An earlyHintsWriter records the status, headers and start of the
+// HTML body of a response.
+type earlyHintsWriter struct {
+	ResponseWriter
+	code int    // final status, or 0
+	html bool   // whether the body is HTML
+	body []byte // start of an HTML body
+}
+
+func (w *earlyHintsWriter) WriteHeader(code int) {
+	if w.code == 0 && code >= 200 {
+		w.code = code
+		mt, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+		w.html = mt == "text/html"
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *earlyHintsWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.WriteHeader(StatusOK)
+		if _, ok := w.Header()["Content-Type"]; !ok {
+			// The server sniffs the content type too.
+			w.html = strings.HasPrefix(DetectContentType(p), "text/html")
+		}
+	}
+	if w.html && len(w.body) < earlyHintsScanSize {
+		w.body = append(w.body, p[:min(len(p), earlyHintsScanSize-len(w.body))]...)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *earlyHintsWriter) Flush() {
+	NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *earlyHintsWriter) Unwrap() ResponseWriter {
+	return w.ResponseWriter
+}
+
+// links returns the links of the recorded response worth hinting.
+func (w *earlyHintsWriter) links() []Link {
+	var links []Link
+	seen := make(map[Link]bool)
+	add := func(l Link) {
+		if l.URL != "" && !seen[l] && len(links) < maxEarlyHintLinks {
+			seen[l] = true
+			links = append(links, l)
+		}
+	}
+	for _, l := range parseLinks(w.Header()["Link"]) {
+		switch l.Rel {
+		case "preload", "modulepreload", "preconnect":
+			add(l)
+		}
+	}
+	if w.html {
+		htmlLinks(string(w.body), add)
+	}
+	return links
+}
+
+// htmlLinks calls add for each resource loaded by the head of the
+// HTML document doc.
+func htmlLinks(doc string, add func(Link)) {
+	z := html.NewTokenizer(strings.NewReader(doc))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return
+		case html.StartTagToken, html.SelfClosingTagToken:
+		default:
+			continue
+		}
+		t := z.Token()
+		attrs := make(map[string]string)
+		for _, a := range t.Attr {
+			attrs[a.Key] = a.Val
+		}
+		l := Link{CrossOrigin: crossOrigin(attrs)}
+		switch t.DataAtom {
+		case atom.Body:
+			return
+		case atom.Script:
+			l.URL, l.As = attrs["src"], "script"
+			if attrs["type"] == "module" {
+				l.Rel = "modulepreload"
+				l.As = ""
+			}
+		case atom.Link:
+			l.URL = attrs["href"]
+			switch strings.ToLower(attrs["rel"]) {
+			case "stylesheet":
+				l.As = "style"
+			case "preload":
+				l.As, l.Type = attrs["as"], attrs["type"]
+			case "modulepreload":
+				l.Rel = "modulepreload"
+			default:
+				continue
+			}
+		default:
+			continue
+		}
+		if l.URL != "" {
+			add(l)
+		}
+	}
+}
+
+// crossOrigin returns the CORS mode set by the crossorigin attribute
+// in attrs.
+func crossOrigin(attrs map[string]string) string {
+	v, ok := attrs["crossorigin"]
+	switch {
+	case !ok:
+		return ""
+	case strings.EqualFold(v, "use-credentials"):
+		return "use-credentials"
+	}
+	return "anonymous"
+}
+
+// parseLinks parses the values of Link headers.
+func parseLinks(values []string) []Link {
+	var links []Link
+	for _, v := range values {
+		for {
+			v = strings.TrimLeft(v, " \t,")
+			if !strings.HasPrefix(v, "<") {
+				break
+			}
+			end := strings.IndexByte(v, '>')
+			if end < 0 {
+				break
+			}
+			l := Link{URL: v[1:end]}
+			v = v[end+1:]
+			var params string
+			params, v = cutLinkParams(v)
+			for p := range strings.SplitSeq(params, ";") {
+				name, val, _ := strings.Cut(p, "=")
+				name = strings.ToLower(strings.TrimSpace(name))
+				val = strings.Trim(strings.TrimSpace(val), `"`)
+				switch name {
+				case "rel":
+					l.Rel = strings.ToLower(val)
+				case "as":
+					l.As = val
+				case "type":
+					l.Type = val
+				case "crossorigin":
+					l.CrossOrigin = crossOrigin(map[string]string{name: val})
+				}
+			}
+			links = append(links, l)
+		}
+	}
+	return links
+}
+
+// cutLinkParams returns the parameters at the start of v, up to the
+// comma ending the link value outside quoted strings, and the rest.
+func cutLinkParams(v string) (params, rest string) {
+	quoted := false
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; {
+		case c == '"':
+			quoted = !quoted
+		case c == '\\' && quoted:
+			i++
+		case c == ',' && !quoted:
+			return v[:i], v[i+1:]
+		}
+	}
+	return v, ""
+}
@@ -0,0 +1,186 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"io"
+	"net/http/httptrace"
+	"net/textproto"
+	"slices"
+	"testing"
+
+	"github.com/johnsiilver/http/httptest"
+)
+
+func TestLinkString(t *testing.T) {
+	for _, tt := range []struct {
+		l    Link
+		want string
+	}{
+		{Link{URL: "/app.css", As: "style"}, "</app.css>; rel=preload; as=style"},
+		{Link{URL: "/font.woff2", As: "font", Type: "font/woff2", CrossOrigin: "anonymous"}, `</font.woff2>; rel=preload; as=font; type="font/woff2"; crossorigin`},
+		{Link{URL: "https://cdn.example", Rel: "preconnect", CrossOrigin: "use-credentials"}, "<https://cdn.example>; rel=preconnect; crossorigin=use-credentials"},
+		{Link{URL: "/m.js", Rel: "modulepreload"}, "</m.js>; rel=modulepreload"},
+	} {
+		if got := tt.l.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q; want %q", tt.l, got, tt.want)
+		}
+	}
+}
+
+func TestParseLinks(t *testing.T) {
+	got := parseLinks([]string{
+		`</a.css>; rel=preload; as=style, </b.woff2>; rel="preload"; as=font; type="font/woff2"; crossorigin`,
+		`<https://cdn.example>; rel=preconnect; title="a, b", </next>; REL=next`,
+		`garbage`,
+	})
+	want := []Link{
+		{URL: "/a.css", Rel: "preload", As: "style"},
+		{URL: "/b.woff2", Rel: "preload", As: "font", Type: "font/woff2", CrossOrigin: "anonymous"},
+		{URL: "https://cdn.example", Rel: "preconnect"},
+		{URL: "/next", Rel: "next"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseLinks:\n%+v\nwant:\n%+v", got, want)
+	}
+	for _, l := range want {
+		if got := parseLinks([]string{l.String()}); len(got) != 1 || got[0] != l {
+			t.Errorf("parseLinks(%q) = %+v; want %+v", l.String(), got, l)
+		}
+	}
+}
+
+func TestHTMLLinks(t *testing.T) {
+	doc := `<!doctype html><html><head>
+<link rel="stylesheet" href="/app.css">
+<link rel=preload href=/font.woff2 as=font type=font/woff2 crossorigin>
+<link rel=icon href=/favicon.ico>
+<link rel=modulepreload href=/dep.js>
+<script src="/app.js"></script>
+<script type="module" src="/main.js" crossorigin="use-credentials"></script>
+<script>inline()</script>
+</head><body><script src="/late.js"></script></body></html>`
+	var got []Link
+	htmlLinks(doc, func(l Link) { got = append(got, l) })
+	want := []Link{
+		{URL: "/app.css", As: "style"},
+		{URL: "/font.woff2", As: "font", Type: "font/woff2", CrossOrigin: "anonymous"},
+		{URL: "/dep.js", Rel: "modulepreload"},
+		{URL: "/app.js", As: "script"},
+		{URL: "/main.js", Rel: "modulepreload", CrossOrigin: "use-credentials"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("htmlLinks:\n%+v\nwant:\n%+v", got, want)
+	}
+}
+
+// plainWriter is a ResponseWriter not known to support interim
+// responses.
+type plainWriter struct {
+	header Header
+	codes  []int
+}
+
+func (w *plainWriter) Header() Header              { return w.header }
+func (w *plainWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *plainWriter) WriteHeader(code int)        { w.codes = append(w.codes, code) }
+
+func TestWriteEarlyHints(t *testing.T) {
+	links := []Link{{URL: "/app.css", As: "style"}, {URL: "/app.js", As: "script"}}
+
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Link", "</other>; rel=next")
+	if !WriteEarlyHints(rec, httptest.NewRequest("GET", "/", nil), links...) {
+		t.Fatal("WriteEarlyHints to a ResponseRecorder = false; want true")
+	}
+	if len(rec.Interim) != 1 || rec.Interim[0].Code != StatusEarlyHints {
+		t.Fatalf("interim responses = %v; want one 103", rec.Interim)
+	}
+	want := []string{"</app.css>; rel=preload; as=style", "</app.js>; rel=preload; as=script"}
+	if got := rec.Interim[0].Header["Link"]; !slices.Equal(got, want) {
+		t.Errorf("103 Link = %q; want %q", got, want)
+	}
+	if got := rec.Header()["Link"]; !slices.Equal(got, []string{"</other>; rel=next"}) {
+		t.Errorf("after hints, Link = %q; want the handler's own", got)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Proto, req.ProtoMinor = "HTTP/1.0", 0
+	if WriteEarlyHints(httptest.NewRecorder(), req, links...) {
+		t.Error("WriteEarlyHints for an HTTP/1.0 request = true; want false")
+	}
+	pw := &plainWriter{header: make(Header)}
+	if WriteEarlyHints(pw, httptest.NewRequest("GET", "/", nil), links...) || len(pw.codes) != 0 {
+		t.Errorf("WriteEarlyHints to an unknown writer wrote %v; want nothing", pw.codes)
+	}
+	if WriteEarlyHints(nil, httptest.NewRequest("GET", "/", nil), links...) {
+		t.Error("WriteEarlyHints to a nil writer = true; want false")
+	}
+}
+
+func TestEarlyHintsHandler(t *testing.T) {
+	page := HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Add("Link", "</font.woff2>; rel=preload; as=font; crossorigin")
+		w.Header().Add("Link", "</next>; rel=next")
+		io.WriteString(w, `<html><head><link rel=stylesheet href=/app.css></head><body>hi</body></html>`)
+	})
+	want := []string{
+		"</font.woff2>; rel=preload; as=font; crossorigin",
+		"</app.css>; rel=preload; as=style",
+	}
+
+	for _, http2 := range []bool{false, true} {
+		name := "HTTP/1.1"
+		if http2 {
+			name = "HTTP/2"
+		}
+		t.Run(name, func(t *testing.T) {
+			ts := httptest.NewUnstartedServer(EarlyHintsHandler(page))
+			ts.EnableHTTP2 = http2
+			ts.StartTLS()
+			defer ts.Close()
+
+			get := func(path string, host ...string) (hints [][]string) {
+				t.Helper()
+				trace := &httptrace.ClientTrace{
+					Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+						if code == StatusEarlyHints {
+							hints = append(hints, header["Link"])
+						}
+						return nil
+					},
+				}
+				ctx := httptrace.WithClientTrace(context.Background(), trace)
+				req, _ := NewRequestWithContext(ctx, "GET", ts.URL+path, nil)
+				if len(host) > 0 {
+					req.Host = host[0]
+				}
+				res, err := ts.Client().Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				io.Copy(io.Discard, res.Body)
+				res.Body.Close()
+				if got := res.ProtoMajor == 2; got != http2 {
+					t.Errorf("response protocol %s", res.Proto)
+				}
+				return hints
+			}
+			if hints := get("/page"); len(hints) != 0 {
+				t.Errorf("first request: hints %q; want none", hints)
+			}
+			if hints := get("/page"); len(hints) != 1 || !slices.Equal(hints[0], want) {
+				t.Errorf("second request: hints %q; want [%q]", hints, want)
+			}
+			if hints := get("/other"); len(hints) != 0 {
+				t.Errorf("other path: hints %q; want none", hints)
+			}
+			if hints := get("/page", "elsewhere.example"); len(hints) != 1 || !slices.Equal(hints[0], want) {
+				t.Errorf("other host: hints %q; want [%q]", hints, want)
+			}
+		})
+	}
+}
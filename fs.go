@@ -68,6 +68,8 @@ type File = http.File
 // The content's Seek method must work: ServeContent uses
 // a seek to the end of the content to determine its size.
 // Note that [*os.File] implements the [io.ReadSeeker] interface.
+// Content that cannot seek, such as an object read from storage with
+// ranged reads, can be served with a [RangeWriter].
 //
 // If the caller has set w's ETag header formatted per RFC 7232, section 2.3,
 // ServeContent uses it to handle requests using If-Match, If-None-Match, or If-Range.
@@ -88,10 +90,6 @@ func ServeContent(w ResponseWriter, req *Request, name string, modtime time.Time
 // users.
 var errSeeker = errors.New("seeker can't seek")
 
-// errNoOverlap is returned by serveContent's parseRange if first-byte-pos of
-// all of the byte-range-spec values is greater than the content size.
-var errNoOverlap = errors.New("invalid range: failed to overlap")
-
 // ServeFile replies to the request with the contents of the named
 // file or directory.
 //
@@ -0,0 +1,296 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package har records and replays HTTP exchanges in the HTTP Archive
+// (HAR) 1.2 format.
+//
+// A [Recorder] is an [http.RoundTripper] that captures every exchange
+// it performs, including connection timings gathered with
+// [httptrace.ClientTrace] hooks. A [Replayer] is an [http.RoundTripper]
+// that answers requests from a previously captured archive, which is
+// useful for deterministic tests.
+//
+// The format is described at http://www.softwareishard.com/blog/har-12-spec/.
+package har
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Version is the HAR format version produced by this package.
+const Version = "1.2"
+
+// HAR is the root object of an HTTP Archive.
+type HAR struct {
+	Log *Log `json:"log"`
+}
+
+// Log holds the exported data of an archive.
+type Log struct {
+	Version string  `json:"version"`
+	Creator Creator `json:"creator"`
+	Entries []Entry `json:"entries"`
+	Comment string  `json:"comment,omitempty"`
+}
+
+// Creator identifies the application that created the archive.
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// An Entry is a single exchange of a request and its response.
+type Entry struct {
+	// StartedDateTime is the time the request was issued.
+	StartedDateTime time.Time `json:"startedDateTime"`
+
+	// Time is the total elapsed time of the request in milliseconds.
+	// It is the sum of all the non-negative Timings, excluding SSL.
+	Time float64 `json:"time"`
+
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+	Cache    struct{} `json:"cache"`
+	Timings  Timings  `json:"timings"`
+
+	// ServerIPAddress is the IP address of the server that was
+	// connected to, if known.
+	ServerIPAddress string `json:"serverIPAddress,omitempty"`
+
+	// Connection identifies the underlying connection by its
+	// local address, if known.
+	Connection string `json:"connection,omitempty"`
+
+	Comment string `json:"comment,omitempty"`
+}
+
+// Request describes a performed request.
+type Request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []Cookie    `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	QueryString []NameValue `json:"queryString"`
+	PostData    *PostData   `json:"postData,omitempty"`
+
+	// HeadersSize is the number of bytes from the start of the
+	// request to the body. It is -1 if unknown.
+	HeadersSize int64 `json:"headersSize"`
+
+	// BodySize is the size of the request body in bytes, or -1 if
+	// unknown.
+	BodySize int64 `json:"bodySize"`
+
+	Comment string `json:"comment,omitempty"`
+}
+
+// Response describes a received response.
+type Response struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []Cookie    `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	Content     Content     `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+
+	// HeadersSize is the number of bytes from the start of the
+	// response to the body. It is -1 if unknown.
+	HeadersSize int64 `json:"headersSize"`
+
+	// BodySize is the size of the received body in bytes, or -1 if
+	// unknown.
+	BodySize int64 `json:"bodySize"`
+
+	Comment string `json:"comment,omitempty"`
+}
+
+// A Cookie is a request or response cookie.
+type Cookie struct {
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Path     string     `json:"path,omitempty"`
+	Domain   string     `json:"domain,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	HTTPOnly bool       `json:"httpOnly,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+}
+
+// A NameValue is a header or query string parameter.
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// PostData describes a request body.
+type PostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+
+	// Encoding is "base64" if Text holds base64-encoded binary data.
+	// This is an extension of the 1.2 format, mirroring
+	// Content.Encoding.
+	Encoding string `json:"_encoding,omitempty"`
+
+	Comment string `json:"comment,omitempty"`
+}
+
+// Content describes a response body.
+type Content struct {
+	// Size is the length of the decoded body in bytes. It may be
+	// larger than the recorded Text if the body was truncated.
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+
+	// Encoding is "base64" if Text holds base64-encoded binary data.
+	Encoding string `json:"encoding,omitempty"`
+
+	Comment string `json:"comment,omitempty"`
+}
+
+// Timings breaks the request time down into phases, in
+// milliseconds. Phases that do not apply are -1.
+type Timings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// Decode reads an archive in JSON form from r.
+func Decode(r io.Reader) (*HAR, error) {
+	h := new(HAR)
+	if err := json.NewDecoder(r).Decode(h); err != nil {
+		return nil, err
+	}
+	if h.Log == nil {
+		h.Log = &Log{Version: Version}
+	}
+	return h, nil
+}
+
+// Encode writes h to w as indented JSON.
+func Encode(w io.Writer, h *HAR) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(h)
+}
+
+// ReadFile reads the archive in the named file.
+func ReadFile(name string) (*HAR, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Decode(f)
+}
+
+// WriteFile writes h to the named file, creating it if necessary.
+func WriteFile(name string, h *HAR) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := Encode(f, h); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// headerList converts h to a list of name/value pairs sorted by name.
+func headerList(h http.Header) []NameValue {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	nv := make([]NameValue, 0, len(h))
+	for _, k := range keys {
+		for _, v := range h[k] {
+			nv = append(nv, NameValue{k, v})
+		}
+	}
+	return nv
+}
+
+// header converts a list of name/value pairs back to an http.Header.
+func header(nv []NameValue) http.Header {
+	h := make(http.Header, len(nv))
+	for _, p := range nv {
+		h.Add(p.Name, p.Value)
+	}
+	return h
+}
+
+func cookieList(cs []*http.Cookie) []Cookie {
+	l := make([]Cookie, 0, len(cs))
+	for _, c := range cs {
+		hc := Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			HTTPOnly: c.HttpOnly,
+			Secure:   c.Secure,
+		}
+		if !c.Expires.IsZero() {
+			t := c.Expires
+			hc.Expires = &t
+		}
+		l = append(l, hc)
+	}
+	return l
+}
+
+// isText reports whether a body of the given media type can be stored
+// as text rather than base64.
+func isText(mimeType string) bool {
+	mt, _, _ := strings.Cut(mimeType, ";")
+	mt = strings.TrimSpace(strings.ToLower(mt))
+	switch {
+	case strings.HasPrefix(mt, "text/"),
+		strings.HasSuffix(mt, "+json"),
+		strings.HasSuffix(mt, "+xml"):
+		return true
+	}
+	switch mt {
+	case "application/json", "application/xml", "application/javascript",
+		"application/x-www-form-urlencoded", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// encodeBody returns the text and encoding with which to store b.
+func encodeBody(mimeType string, b []byte) (text, encoding string) {
+	if len(b) == 0 {
+		return "", ""
+	}
+	if isText(mimeType) {
+		return string(b), ""
+	}
+	return base64.StdEncoding.EncodeToString(b), "base64"
+}
+
+// decodeBody is the inverse of encodeBody.
+func decodeBody(text, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		return base64.StdEncoding.DecodeString(text)
+	}
+	return []byte(text), nil
+}
@@ -0,0 +1,189 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package har
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/johnsiilver/http/httptest"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	n := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/text":
+			n++
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+			w.Header().Set("Content-Type", "text/plain")
+			io.WriteString(w, "hello "+strings.Repeat("!", n))
+		case "/binary":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte{0, 1, 2, 3, 0xff})
+		case "/echo":
+			w.Header().Set("Content-Type", "text/plain")
+			io.Copy(w, r.Body)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func get(t *testing.T, c *http.Client, req *http.Request) (*http.Response, string) {
+	t.Helper()
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, string(b)
+}
+
+func TestRecorder(t *testing.T) {
+	ts := newTestServer(t)
+	rec := &Recorder{Transport: ts.Client().Transport, MaxBodySize: 4}
+	c := &http.Client{Transport: rec}
+
+	req, _ := http.NewRequest("GET", ts.URL+"/text?a=1&b=2", nil)
+	req.AddCookie(&http.Cookie{Name: "c", Value: "d"})
+	get(t, c, req)
+	req, _ = http.NewRequest("POST", ts.URL+"/echo", strings.NewReader("abcdefgh"))
+	req.Header.Set("Content-Type", "text/plain")
+	get(t, c, req)
+
+	h := rec.HAR()
+	if got, want := len(h.Log.Entries), 2; got != want {
+		t.Fatalf("got %d entries; want %d", got, want)
+	}
+	e := h.Log.Entries[0]
+	if e.Request.Method != "GET" || e.Request.URL != ts.URL+"/text?a=1&b=2" {
+		t.Errorf("request = %s %s", e.Request.Method, e.Request.URL)
+	}
+	if got := e.Request.QueryString; len(got) != 2 || got[0] != (NameValue{"a", "1"}) {
+		t.Errorf("query string = %v", got)
+	}
+	if got := e.Request.Cookies; len(got) != 1 || got[0].Name != "c" {
+		t.Errorf("request cookies = %v", got)
+	}
+	if got := e.Response.Cookies; len(got) != 1 || got[0].Name != "session" || got[0].Value != "abc" {
+		t.Errorf("response cookies = %v", got)
+	}
+	if e.Response.Status != 200 || e.Response.Content.Size != 7 {
+		t.Errorf("response status %d size %d; want 200, 7", e.Response.Status, e.Response.Content.Size)
+	}
+	if got, want := e.Response.Content.Text, "hell"; got != want || e.Response.Content.Comment != "truncated" {
+		t.Errorf("content = %q (%q); want %q, truncated", got, e.Response.Content.Comment, want)
+	}
+	if e.Timings.Wait < 0 || e.Timings.Send < 0 || e.Timings.Receive < 0 || e.Time <= 0 {
+		t.Errorf("timings = %+v, time %v; want non-negative phases", e.Timings, e.Time)
+	}
+	if e.ServerIPAddress != "127.0.0.1" {
+		t.Errorf("ServerIPAddress = %q; want 127.0.0.1", e.ServerIPAddress)
+	}
+
+	pd := h.Log.Entries[1].Request.PostData
+	if pd == nil || pd.Text != "abcd" || pd.Comment != "truncated" || h.Log.Entries[1].Request.BodySize != 8 {
+		t.Errorf("post data = %+v", pd)
+	}
+
+	rec.Reset()
+	if n := len(rec.HAR().Log.Entries); n != 0 {
+		t.Errorf("after Reset, %d entries; want 0", n)
+	}
+}
+
+func TestRecordError(t *testing.T) {
+	rec := &Recorder{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("boom")
+	})}
+	req, _ := http.NewRequest("GET", "http://example.com/", nil)
+	if _, err := rec.RoundTrip(req); err == nil {
+		t.Fatal("RoundTrip succeeded; want error")
+	}
+	e := rec.HAR().Log.Entries
+	if len(e) != 1 || e[0].Response.Status != 0 || e[0].Response.Comment != "boom" {
+		t.Fatalf("entries = %+v", e)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestRecordReplay(t *testing.T) {
+	ts := newTestServer(t)
+	rec := &Recorder{Transport: ts.Client().Transport}
+	c := &http.Client{Transport: rec}
+	for _, path := range []string{"/text", "/text", "/binary"} {
+		req, _ := http.NewRequest("GET", ts.URL+path, nil)
+		get(t, c, req)
+	}
+	req, _ := http.NewRequest("POST", ts.URL+"/echo", strings.NewReader("one"))
+	get(t, c, req)
+	req, _ = http.NewRequest("POST", ts.URL+"/echo", strings.NewReader("two"))
+	get(t, c, req)
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, rec.HAR()); err != nil {
+		t.Fatal(err)
+	}
+	h, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Log.Entries[2].Response.Content.Encoding; got != "base64" {
+		t.Errorf("binary content encoding = %q; want base64", got)
+	}
+
+	rp := NewReplayer(h)
+	rc := &http.Client{Transport: rp}
+	tests := []struct {
+		method, path, body string
+		want               string
+	}{
+		{"GET", "/text", "", "hello !"},
+		{"GET", "/text", "", "hello !!"},
+		{"GET", "/text", "", "hello !!"}, // last match repeats
+		{"GET", "/binary", "", "\x00\x01\x02\x03\xff"},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, ts.URL+tt.path, nil)
+		res, body := get(t, rc, req)
+		if body != tt.want {
+			t.Errorf("%s %s = %q; want %q", tt.method, tt.path, body, tt.want)
+		}
+		if res.StatusCode != 200 || res.Status != "200 OK" {
+			t.Errorf("%s %s status = %q", tt.method, tt.path, res.Status)
+		}
+	}
+
+	rp.Match = MatchAll(MatchMethod, MatchPath, MatchBody)
+	req, _ = http.NewRequest("POST", "http://other.example/echo", strings.NewReader("two"))
+	if _, body := get(t, rc, req); body != "two" {
+		t.Errorf("body-matched POST = %q; want %q", body, "two")
+	}
+
+	req, _ = http.NewRequest("GET", ts.URL+"/missing", nil)
+	if _, err := rc.Do(req); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("unmatched request error = %v; want ErrNoMatch", err)
+	}
+	rp.Fallback = ts.Client().Transport
+	req, _ = http.NewRequest("GET", ts.URL+"/missing", nil)
+	if res, _ := get(t, rc, req); res.StatusCode != 404 {
+		t.Errorf("fallback status = %d; want 404", res.StatusCode)
+	}
+}
@@ -0,0 +1,333 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package har
+
+import (
+	"cmp"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultMaxBodySize is the default limit on the number of body bytes
+// a [Recorder] stores for each request and response.
+const DefaultMaxBodySize = 1 << 20
+
+// A Recorder is an [http.RoundTripper] that records every exchange
+// it performs as a HAR [Entry].
+//
+// An entry is completed when the response body has been read to EOF
+// or closed, or when the round trip fails. Recorders are safe for
+// concurrent use by multiple goroutines.
+type Recorder struct {
+	// Transport is the RoundTripper that performs the requests.
+	// If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// MaxBodySize limits the number of bytes of each request and
+	// response body that are stored in the archive. Bodies longer
+	// than this are truncated and annotated with a comment.
+	// If zero, DefaultMaxBodySize is used. If negative, bodies are
+	// not stored, although their sizes are still recorded.
+	MaxBodySize int64
+
+	mu      sync.Mutex
+	entries []Entry
+}
+
+func (r *Recorder) transport() http.RoundTripper {
+	if r.Transport != nil {
+		return r.Transport
+	}
+	return http.DefaultTransport
+}
+
+func (r *Recorder) maxBodySize() int64 {
+	if r.MaxBodySize == 0 {
+		return DefaultMaxBodySize
+	}
+	return max(r.MaxBodySize, 0)
+}
+
+// HAR returns an archive of the exchanges recorded so far, ordered by
+// the time each request was started.
+func (r *Recorder) HAR() *HAR {
+	r.mu.Lock()
+	entries := slices.Clone(r.entries)
+	r.mu.Unlock()
+	slices.SortStableFunc(entries, func(a, b Entry) int {
+		return a.StartedDateTime.Compare(b.StartedDateTime)
+	})
+	if entries == nil {
+		entries = []Entry{}
+	}
+	return &HAR{Log: &Log{
+		Version: Version,
+		Creator: Creator{Name: "github.com/johnsiilver/http/har", Version: Version},
+		Entries: entries,
+	}}
+}
+
+// Reset discards all recorded entries.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+func (r *Recorder) add(e Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, e)
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	limit := r.maxBodySize()
+	ex := &exchange{rec: r, start: time.Now()}
+
+	ctx := httptrace.WithClientTrace(req.Context(), ex.clientTrace())
+	outreq := req.Clone(ctx)
+	if req.Body != nil && req.Body != http.NoBody {
+		ex.reqBody = &captureBody{ReadCloser: req.Body, limit: limit}
+		outreq.Body = ex.reqBody
+	}
+	ex.entry.StartedDateTime = ex.start
+	ex.entry.Request = requestEntry(req)
+
+	res, err := r.transport().RoundTrip(outreq)
+	if err != nil {
+		ex.entry.Response = Response{HeadersSize: -1, BodySize: -1, Comment: err.Error()}
+		ex.finish(time.Now())
+		return nil, err
+	}
+	ex.entry.Response = responseEntry(res)
+	ex.resBody = &captureBody{ReadCloser: res.Body, limit: limit, done: ex.finish}
+	res.Body = ex.resBody
+	return res, nil
+}
+
+// An exchange accumulates the state of a single recorded round trip.
+type exchange struct {
+	rec     *Recorder
+	start   time.Time
+	entry   Entry
+	reqBody *captureBody
+	resBody *captureBody
+
+	mu           sync.Mutex // guards the fields below, set by trace hooks
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	remoteAddr   net.Addr
+	localAddr    net.Addr
+	finished     bool
+}
+
+func (ex *exchange) clientTrace() *httptrace.ClientTrace {
+	set := func(p *time.Time) {
+		ex.mu.Lock()
+		if p.IsZero() {
+			*p = time.Now()
+		}
+		ex.mu.Unlock()
+	}
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { set(&ex.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { set(&ex.dnsDone) },
+		ConnectStart:         func(string, string) { set(&ex.connectStart) },
+		ConnectDone:          func(string, string, error) { set(&ex.connectDone) },
+		TLSHandshakeStart:    func() { set(&ex.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { set(&ex.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { set(&ex.wroteRequest) },
+		GotFirstResponseByte: func() { set(&ex.firstByte) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			set(&ex.gotConn)
+			ex.mu.Lock()
+			if info.Conn != nil {
+				ex.remoteAddr = info.Conn.RemoteAddr()
+				ex.localAddr = info.Conn.LocalAddr()
+			}
+			ex.mu.Unlock()
+		},
+	}
+}
+
+// millis returns the duration from a to b in milliseconds, or -1 if
+// either time is unset.
+func millis(a, b time.Time) float64 {
+	if a.IsZero() || b.IsZero() {
+		return -1
+	}
+	return float64(b.Sub(a)) / float64(time.Millisecond)
+}
+
+// finish completes the entry at time end and adds it to the recorder.
+// Only the first call has any effect.
+func (ex *exchange) finish(end time.Time) {
+	ex.mu.Lock()
+	if ex.finished {
+		ex.mu.Unlock()
+		return
+	}
+	ex.finished = true
+
+	e := &ex.entry
+	t := &e.Timings
+	firstNetEvent := cmp.Or(ex.dnsStart, ex.connectStart, ex.gotConn)
+	t.Blocked = millis(ex.start, firstNetEvent)
+	t.DNS = millis(ex.dnsStart, ex.dnsDone)
+	t.Connect = millis(ex.connectStart, cmp.Or(ex.tlsDone, ex.connectDone))
+	t.SSL = millis(ex.tlsStart, ex.tlsDone)
+	t.Send = millis(ex.gotConn, ex.wroteRequest)
+	t.Wait = millis(ex.wroteRequest, ex.firstByte)
+	t.Receive = millis(ex.firstByte, end)
+	if ex.resBody == nil {
+		t.Receive = -1
+	}
+	for _, v := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait, t.Receive} {
+		if v > 0 {
+			e.Time += v
+		}
+	}
+	if ta, ok := ex.remoteAddr.(*net.TCPAddr); ok {
+		e.ServerIPAddress = ta.IP.String()
+	}
+	if ex.localAddr != nil {
+		e.Connection = ex.localAddr.String()
+	}
+	ex.mu.Unlock()
+
+	if b := ex.reqBody; b != nil {
+		data, n, truncated := b.result()
+		e.Request.BodySize = n
+		pd := &PostData{MimeType: ex.entry.Request.mimeType()}
+		pd.Text, pd.Encoding = encodeBody(pd.MimeType, data)
+		if truncated {
+			pd.Comment = "truncated"
+		}
+		e.Request.PostData = pd
+	}
+	if b := ex.resBody; b != nil {
+		data, n, truncated := b.result()
+		e.Response.BodySize = n
+		c := &e.Response.Content
+		c.Size = n
+		c.Text, c.Encoding = encodeBody(c.MimeType, data)
+		if truncated {
+			c.Comment = "truncated"
+		}
+	}
+	ex.rec.add(*e)
+}
+
+func (r *Request) mimeType() string {
+	for _, h := range r.Headers {
+		if h.Name == "Content-Type" {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+func requestEntry(req *http.Request) Request {
+	hr := Request{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: req.Proto,
+		Cookies:     cookieList(req.Cookies()),
+		Headers:     headerList(req.Header),
+		QueryString: headerList(http.Header(req.URL.Query())),
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+	if hr.Method == "" {
+		hr.Method = http.MethodGet
+	}
+	if hr.HTTPVersion == "" {
+		hr.HTTPVersion = "HTTP/1.1"
+	}
+	if req.Host != "" && req.Host != req.URL.Host {
+		hr.Headers = append(hr.Headers, NameValue{"Host", req.Host})
+	}
+	return hr
+}
+
+func responseEntry(res *http.Response) Response {
+	hr := Response{
+		Status:      res.StatusCode,
+		StatusText:  http.StatusText(res.StatusCode),
+		HTTPVersion: res.Proto,
+		Cookies:     cookieList(res.Cookies()),
+		Headers:     headerList(res.Header),
+		HeadersSize: -1,
+		BodySize:    -1,
+		Content: Content{
+			Size:     -1,
+			MimeType: res.Header.Get("Content-Type"),
+		},
+	}
+	if loc, err := res.Location(); err == nil {
+		hr.RedirectURL = loc.String()
+	}
+	return hr
+}
+
+// A captureBody wraps a request or response body, keeping a copy of
+// up to limit bytes read through it.
+type captureBody struct {
+	io.ReadCloser
+	limit int64
+	done  func(time.Time) // if non-nil, called at EOF or Close
+
+	mu        sync.Mutex
+	buf       []byte
+	n         int64
+	truncated bool
+}
+
+func (b *captureBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mu.Lock()
+	b.n += int64(n)
+	if room := b.limit - int64(len(b.buf)); room > 0 {
+		b.buf = append(b.buf, p[:min(int64(n), room)]...)
+	}
+	if int64(n) > 0 && b.n > b.limit {
+		b.truncated = true
+	}
+	b.mu.Unlock()
+	if err == io.EOF && b.done != nil {
+		b.done(time.Now())
+	}
+	return n, err
+}
+
+func (b *captureBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.done != nil {
+		b.done(time.Now())
+	}
+	return err
+}
+
+// result returns the captured bytes, the number of bytes read, and
+// whether the capture was truncated.
+func (b *captureBody) result() ([]byte, int64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.buf), b.n, b.truncated
+}
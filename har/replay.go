@@ -0,0 +1,227 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package har
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// ErrNoMatch is returned by [Replayer.RoundTrip] when no recorded
+// entry matches a request and no Fallback is configured.
+var ErrNoMatch = errors.New("har: no recorded entry matches request")
+
+// A Matcher reports whether the recorded entry e should be used to
+// answer req. body is the request body, which has already been read.
+type Matcher func(req *http.Request, body []byte, e *Entry) bool
+
+// MatchMethod matches entries with the same request method.
+func MatchMethod(req *http.Request, body []byte, e *Entry) bool {
+	m := req.Method
+	if m == "" {
+		m = http.MethodGet
+	}
+	return m == e.Request.Method
+}
+
+// MatchURL matches entries with an identical request URL.
+func MatchURL(req *http.Request, body []byte, e *Entry) bool {
+	return req.URL.String() == e.Request.URL
+}
+
+// MatchPath matches entries with the same URL path, ignoring the
+// scheme, host and query.
+func MatchPath(req *http.Request, body []byte, e *Entry) bool {
+	u, err := url.Parse(e.Request.URL)
+	return err == nil && u.Path == req.URL.Path
+}
+
+// MatchQuery matches entries with the same set of query parameters,
+// regardless of their order.
+func MatchQuery(req *http.Request, body []byte, e *Entry) bool {
+	u, err := url.Parse(e.Request.URL)
+	return err == nil && u.Query().Encode() == req.URL.Query().Encode()
+}
+
+// MatchBody matches entries whose recorded request body is identical
+// to the request body.
+func MatchBody(req *http.Request, body []byte, e *Entry) bool {
+	var recorded []byte
+	if pd := e.Request.PostData; pd != nil {
+		var err error
+		if recorded, err = decodeBody(pd.Text, pd.Encoding); err != nil {
+			return false
+		}
+	}
+	return bytes.Equal(body, recorded)
+}
+
+// MatchHeaders returns a Matcher that matches entries whose recorded
+// values for the named request headers equal the request's.
+func MatchHeaders(names ...string) Matcher {
+	return func(req *http.Request, body []byte, e *Entry) bool {
+		h := header(e.Request.Headers)
+		for _, name := range names {
+			if fmt.Sprint(h.Values(name)) != fmt.Sprint(req.Header.Values(name)) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// MatchAll returns a Matcher that matches if all of ms match.
+func MatchAll(ms ...Matcher) Matcher {
+	return func(req *http.Request, body []byte, e *Entry) bool {
+		for _, m := range ms {
+			if !m(req, body, e) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// DefaultMatcher is the Matcher used by a [Replayer] whose Match field
+// is nil. It matches on the request method and URL.
+var DefaultMatcher = MatchAll(MatchMethod, MatchURL)
+
+// A Replayer is an [http.RoundTripper] that answers requests with
+// responses from a recorded archive instead of the network.
+//
+// Entries are considered in archive order. Each entry is used at most
+// once while another unused entry also matches, so a sequence of
+// identical requests is answered with the corresponding sequence of
+// recorded responses; once all matching entries have been used, the
+// last one is repeated.
+//
+// Replayers are safe for concurrent use by multiple goroutines.
+type Replayer struct {
+	// Match selects the entry with which to answer a request.
+	// If nil, DefaultMatcher is used.
+	Match Matcher
+
+	// Fallback, if non-nil, handles requests that match no entry.
+	// If nil, such requests fail with ErrNoMatch.
+	Fallback http.RoundTripper
+
+	mu      sync.Mutex
+	entries []Entry
+	used    []bool
+}
+
+// NewReplayer returns a Replayer serving the entries of h.
+func NewReplayer(h *HAR) *Replayer {
+	var entries []Entry
+	if h != nil && h.Log != nil {
+		entries = h.Log.Entries
+	}
+	return &Replayer{
+		entries: entries,
+		used:    make([]bool, len(entries)),
+	}
+}
+
+// Reset marks every entry as unused.
+func (r *Replayer) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(r.used)
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	e := r.find(req, body)
+	if e == nil {
+		if r.Fallback != nil {
+			if req.Body != nil {
+				req = req.Clone(req.Context())
+				req.Body = io.NopCloser(bytes.NewReader(body))
+			}
+			return r.Fallback.RoundTrip(req)
+		}
+		return nil, fmt.Errorf("%w: %s %s", ErrNoMatch, req.Method, req.URL)
+	}
+	return newResponse(req, e)
+}
+
+// find returns the entry to use for req, or nil if none matches.
+func (r *Replayer) find(req *http.Request, body []byte) *Entry {
+	match := r.Match
+	if match == nil {
+		match = DefaultMatcher
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last := -1
+	for i := range r.entries {
+		if !match(req, body, &r.entries[i]) {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return &r.entries[i]
+		}
+		last = i
+	}
+	if last < 0 {
+		return nil
+	}
+	return &r.entries[last]
+}
+
+// newResponse builds the response recorded in e as a reply to req.
+func newResponse(req *http.Request, e *Entry) (*http.Response, error) {
+	hr := &e.Response
+	if hr.Status == 0 {
+		return nil, fmt.Errorf("har: recorded request failed: %s", hr.Comment)
+	}
+	body, err := decodeBody(hr.Content.Text, hr.Content.Encoding)
+	if err != nil {
+		return nil, fmt.Errorf("har: decoding recorded body: %w", err)
+	}
+	proto := hr.HTTPVersion
+	major, minor, ok := http.ParseHTTPVersion(proto)
+	if !ok {
+		proto, major, minor = "HTTP/1.1", 1, 1
+	}
+	h := header(hr.Headers)
+	h.Del("Transfer-Encoding")
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	statusText := hr.StatusText
+	if statusText == "" {
+		statusText = http.StatusText(hr.Status)
+	}
+	res := &http.Response{
+		Status:        strconv.Itoa(hr.Status) + " " + statusText,
+		StatusCode:    hr.Status,
+		Proto:         proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	if req.Method == http.MethodHead {
+		res.Body = http.NoBody
+	}
+	return res, nil
+}
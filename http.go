@@ -197,6 +197,11 @@ type PushOptions struct {
 // Pusher is the interface implemented by ResponseWriters that support
 // HTTP/2 server push. For more background, see
 // https://tools.ietf.org/html/rfc7540#section-8.2.
+//
+// Most browsers no longer support server push. [WriteEarlyHints] and
+// [EarlyHintsHandler] send 103 (Early Hints) responses instead, which
+// let clients fetch the resources a page needs early over both HTTP/1.1
+// and HTTP/2.
 type Pusher interface {
 	// Push initiates an HTTP/2 server push. This constructs a synthetic
 	// request using the given target and options, serializes that request
@@ -0,0 +1,201 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Ephemeral certificates and mutual TLS for Server
+
+package httptest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+)
+
+// A CA is an ephemeral certificate authority for tests. Its key
+// exists only in memory, and the certificates it issues are valid for
+// a day.
+type CA struct {
+	// Certificate is the CA's self-signed certificate.
+	Certificate *x509.Certificate
+
+	key *ecdsa.PrivateKey
+}
+
+// NewCA generates and returns a new CA.
+func NewCA() *CA {
+	key := newKey()
+	tmpl := certTemplate()
+	tmpl.Subject = pkix.Name{Organization: []string{"httptest"}, CommonName: "httptest CA"}
+	tmpl.IsCA = true
+	tmpl.BasicConstraintsValid = true
+	tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		panic(fmt.Sprintf("httptest: NewCA: %v", err))
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(fmt.Sprintf("httptest: NewCA: %v", err))
+	}
+	return &CA{Certificate: cert, key: key}
+}
+
+// Pool returns a new certificate pool containing ca's certificate.
+func (ca *CA) Pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Certificate)
+	return pool
+}
+
+// IssueServerCert returns a new server certificate signed by ca for
+// the given hosts, which may be DNS names or IP addresses.
+func (ca *CA) IssueServerCert(hosts ...string) tls.Certificate {
+	tmpl := certTemplate()
+	tmpl.Subject = pkix.Name{Organization: []string{"httptest"}}
+	if len(hosts) > 0 {
+		tmpl.Subject.CommonName = hosts[0]
+	}
+	tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	return ca.issue(tmpl)
+}
+
+// IssueClientCert returns a new client certificate signed by ca with
+// the given subject common name.
+func (ca *CA) IssueClientCert(commonName string) tls.Certificate {
+	tmpl := certTemplate()
+	tmpl.Subject = pkix.Name{Organization: []string{"httptest"}, CommonName: commonName}
+	tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	return ca.issue(tmpl)
+}
+
+func (ca *CA) issue(tmpl *x509.Certificate) tls.Certificate {
+	key := newKey()
+	tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.Certificate, &key.PublicKey, ca.key)
+	if err != nil {
+		panic(fmt.Sprintf("httptest: issuing certificate: %v", err))
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(fmt.Sprintf("httptest: issuing certificate: %v", err))
+	}
+	return tls.Certificate{
+		Certificate: [][]byte{der, ca.Certificate.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+}
+
+func newKey() *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(fmt.Sprintf("httptest: generating key: %v", err))
+	}
+	return key
+}
+
+func certTemplate() *x509.Certificate {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		panic(fmt.Sprintf("httptest: generating serial number: %v", err))
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(24 * time.Hour),
+	}
+}
+
+// serverHosts are the names for which a Server's certificate issued
+// by its CA is valid, matching the default test certificate.
+var serverHosts = []string{"127.0.0.1", "::1", "localhost", "example.com"}
+
+// ClientWithCert returns a new HTTP client configured like the one
+// returned by [Server.Client], but presenting cert to the server. Like
+// the Server's Client, its idle connections are closed on
+// [Server.Close]. It must be called after StartTLS.
+func (s *Server) ClientWithCert(cert tls.Certificate) *http.Client {
+	if s.rootCAs == nil {
+		panic("httptest: ClientWithCert on a Server not started with StartTLS")
+	}
+	t := s.newTLSTransport(cert)
+	s.mu.Lock()
+	s.extraTransports = append(s.extraTransports, t)
+	s.mu.Unlock()
+	return &http.Client{Transport: t}
+}
+
+// NewClientCert issues a client certificate with the given common
+// name from the server's CA and returns a client presenting it,
+// together with the certificate. It must be called after StartTLS on
+// a server with a CA.
+func (s *Server) NewClientCert(commonName string) (*http.Client, *x509.Certificate) {
+	if s.CA == nil {
+		panic("httptest: NewClientCert on a Server without a CA")
+	}
+	cert := s.CA.IssueClientCert(commonName)
+	return s.ClientWithCert(cert), cert.Leaf
+}
+
+// SetCertificate replaces the certificate the server presents in new
+// TLS handshakes, for testing how clients handle certificate
+// rotation. It must be called after StartTLS, and cannot be used if
+// the server's TLS config sets GetConfigForClient.
+//
+// The Server's clients continue to trust the server only if cert is
+// issued by the server's CA.
+func (s *Server) SetCertificate(cert tls.Certificate) {
+	if !s.rotatable {
+		panic("httptest: SetCertificate on a Server not started with StartTLS or with its own GetConfigForClient")
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		panic(fmt.Sprintf("httptest: SetCertificate: %v", err))
+	}
+	cfg := s.TLS.Clone()
+	cfg.GetConfigForClient = nil
+	cfg.Certificates = []tls.Certificate{cert}
+	s.certMu.Lock()
+	defer s.certMu.Unlock()
+	s.certificate = leaf
+	s.rotatedTLS = cfg
+}
+
+// getConfigForClient is the GetConfigForClient hook of a TLS Server,
+// returning the config for the certificate set by SetCertificate.
+func (s *Server) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	s.certMu.Lock()
+	defer s.certMu.Unlock()
+	return s.rotatedTLS, nil
+}
+
+// newTLSTransport returns a transport trusting the server, presenting
+// certs to it.
+func (s *Server) newTLSTransport(certs ...tls.Certificate) *http.Transport {
+	t := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			RootCAs:      s.rootCAs,
+			Certificates: certs,
+		},
+		ForceAttemptHTTP2: s.EnableHTTP2,
+	}
+	s.dialMem(t)
+	return t
+}
@@ -0,0 +1,83 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httptest
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestServerMutualTLS(t *testing.T) {
+	ts := NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	ts.RequireClientCert = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	get := func(c *http.Client) (string, error) {
+		res, err := c.Get(ts.URL)
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		return string(b), err
+	}
+	if cn, err := get(ts.Client()); err != nil || cn != "client" {
+		t.Errorf("Client: got %q, %v; want %q", cn, err, "client")
+	}
+	c, cert := ts.NewClientCert("alice")
+	if cn, err := get(c); err != nil || cn != "alice" || cert.Subject.CommonName != "alice" {
+		t.Errorf("NewClientCert client: got %q, %v; want %q", cn, err, "alice")
+	}
+	if _, err := get(ts.ClientWithCert(NewCA().IssueClientCert("mallory"))); err == nil {
+		t.Errorf("client with certificate from another CA succeeded; want error")
+	}
+	noCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: ts.CA.Pool()}}}
+	if _, err := get(noCert); err == nil {
+		t.Errorf("client without certificate succeeded; want error")
+	}
+}
+
+func TestServerSetCertificate(t *testing.T) {
+	ts := NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.CA = NewCA()
+	ts.StartTLS()
+	defer ts.Close()
+
+	serial := func() (string, error) {
+		ts.Client().CloseIdleConnections()
+		res, err := ts.Client().Get(ts.URL)
+		if err != nil {
+			return "", err
+		}
+		res.Body.Close()
+		return res.TLS.PeerCertificates[0].SerialNumber.String(), nil
+	}
+	first, err := serial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != ts.Certificate().SerialNumber.String() {
+		t.Errorf("served certificate %s; Certificate reports %s", first, ts.Certificate().SerialNumber)
+	}
+
+	ts.SetCertificate(ts.CA.IssueServerCert("127.0.0.1"))
+	second, err := serial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second == first || second != ts.Certificate().SerialNumber.String() {
+		t.Errorf("after rotation served %s (was %s); Certificate reports %s", second, first, ts.Certificate().SerialNumber)
+	}
+
+	ts.SetCertificate(NewCA().IssueServerCert("127.0.0.1"))
+	if _, err := serial(); err == nil {
+		t.Errorf("request after rotating to an untrusted certificate succeeded; want error")
+	}
+}
@@ -0,0 +1,601 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package conformance checks HTTP/1.1 servers and handlers against
+// edge cases of RFC 9110 and RFC 9112.
+//
+// A [Suite] sends each of its [Case]s to a server, some as raw bytes
+// on a new connection and some with an [http.Client], and reports
+// whether the server's response conforms:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.TestHandler(t, myHandler)
+//	}
+//
+// Cases that depend on optional features, such as range requests or
+// validators, are skipped when the resource under test does not use
+// them.
+//
+// A server that cannot be reached over the network, such as a handler
+// called in memory, can be checked by setting [Suite.Transport]. The
+// cases that send raw bytes check how the server parses the wire
+// format, which a RoundTripper does not expose, so they are skipped.
+package conformance
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnsiilver/http/httptest"
+)
+
+// A Suite is a set of conformance cases run against one server.
+type Suite struct {
+	// URL is the base URL of the server, such as "http://127.0.0.1:8080".
+	// Its scheme must be http or https.
+	URL string
+
+	// Path is the path of a resource that responds to GET with a
+	// body. If empty, "/" is used.
+	Path string
+
+	// Client makes the requests that do not need a raw connection.
+	// If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// Transport, if non-nil, makes the requests that do not need a
+	// raw connection in place of Client, and the cases that need one
+	// are skipped. URL then need not be reachable, but is still used
+	// to form the URLs of requests.
+	Transport http.RoundTripper
+
+	// TLSConfig configures raw connections to an https URL.
+	TLSConfig *tls.Config
+
+	// Timeout limits each case. If zero, 5 seconds is used.
+	Timeout time.Duration
+
+	// Cases are the cases to run. If nil, Cases is used.
+	Cases []*Case
+}
+
+// A Case is one conformance check.
+type Case struct {
+	// Name identifies the case, such as "duplicate-content-length".
+	Name string
+
+	// Spec cites the requirement checked, such as "RFC 9112 §6.3".
+	Spec string
+
+	// Description describes the request sent and the response required.
+	Description string
+
+	run func(ctx context.Context, s *Suite) error
+}
+
+// An Outcome is the outcome of a case.
+type Outcome int
+
+const (
+	Pass Outcome = iota
+	Fail
+	Skip // the server does not use the feature checked
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Pass:
+		return "PASS"
+	case Fail:
+		return "FAIL"
+	case Skip:
+		return "SKIP"
+	}
+	return "Outcome(" + strconv.Itoa(int(o)) + ")"
+}
+
+// A Result is the result of running a case.
+type Result struct {
+	Case    *Case
+	Outcome Outcome
+	Err     error // why the case failed or was skipped
+}
+
+func (r Result) String() string {
+	s := fmt.Sprintf("%v %s (%s)", r.Outcome, r.Case.Name, r.Case.Spec)
+	if r.Err != nil {
+		s += ": " + r.Err.Error()
+	}
+	return s
+}
+
+// skipError is returned by a case that does not apply to the server.
+type skipError string
+
+func (e skipError) Error() string { return string(e) }
+
+func skipf(format string, args ...any) error {
+	return skipError(fmt.Sprintf(format, args...))
+}
+
+// Run runs the suite's cases in order and returns their results.
+func (s *Suite) Run(ctx context.Context) []Result {
+	var results []Result
+	for _, c := range s.cases() {
+		results = append(results, s.runCase(ctx, c))
+	}
+	return results
+}
+
+// Test runs each of the suite's cases as a subtest of t, which fails
+// if the server does not conform.
+func (s *Suite) Test(t *testing.T) {
+	t.Helper()
+	for _, c := range s.cases() {
+		t.Run(c.Name, func(t *testing.T) {
+			r := s.runCase(t.Context(), c)
+			switch r.Outcome {
+			case Fail:
+				t.Errorf("%s: %v\n%s", c.Spec, r.Err, c.Description)
+			case Skip:
+				t.Skip(r.Err)
+			}
+		})
+	}
+}
+
+func (s *Suite) cases() []*Case {
+	if s.Cases != nil {
+		return s.Cases
+	}
+	return Cases
+}
+
+func (s *Suite) runCase(ctx context.Context, c *Case) Result {
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := c.run(ctx, s)
+	var skip skipError
+	switch {
+	case err == nil:
+		return Result{Case: c, Outcome: Pass}
+	case errors.As(err, &skip):
+		return Result{Case: c, Outcome: Skip, Err: err}
+	}
+	return Result{Case: c, Outcome: Fail, Err: err}
+}
+
+// RunHandler runs the default cases against h, served by an
+// [httptest.Server], and returns their results. h is requested at
+// the path "/".
+func RunHandler(h http.Handler) []Result {
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+	return (&Suite{URL: ts.URL, Client: ts.Client()}).Run(context.Background())
+}
+
+// TestHandler runs the default cases against h, served by an
+// [httptest.Server], as subtests of t. h is requested at the path "/".
+func TestHandler(t *testing.T, h http.Handler) {
+	t.Helper()
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+	(&Suite{URL: ts.URL, Client: ts.Client()}).Test(t)
+}
+
+// Cases are the default cases run by a Suite.
+var Cases = []*Case{
+	{
+		Name:        "duplicate-content-length",
+		Spec:        "RFC 9112 §6.3",
+		Description: "A request with two different Content-Length values must be rejected with 400 (Bad Request).",
+		run: func(ctx context.Context, s *Suite) error {
+			return s.expectStatus(ctx, "POST", "Content-Length: 3\r\nContent-Length: 5\r\n", "abcde", 400)
+		},
+	},
+	{
+		Name:        "obs-fold",
+		Spec:        "RFC 9112 §5.2",
+		Description: "A request with a header line folded with obs-fold must be rejected with 400 (Bad Request) or have the fold replaced by spaces. A successful response passes only if it echoes the header's value, so that the fold can be checked.",
+		run: func(ctx context.Context, s *Suite) error {
+			res, err := s.rawRequest(ctx, "GET", s.path(), "X-Folded: "+foldStart+"\r\n "+foldEnd+"\r\n", "")
+			if err != nil {
+				return err
+			}
+			switch {
+			case res.StatusCode == 400:
+				return nil
+			case res.StatusCode < 200 || res.StatusCode > 299:
+				return fmt.Errorf("got status %s; want 400 or a successful response", res.Status)
+			}
+			return checkUnfolded(res)
+		},
+	},
+	{
+		Name:        "invalid-method",
+		Spec:        "RFC 9112 §3",
+		Description: "A request whose method is not a token must be rejected with 400 (Bad Request).",
+		run: func(ctx context.Context, s *Suite) error {
+			return s.expectStatus(ctx, "G(T", "", "", 400)
+		},
+	},
+	{
+		Name:        "head-no-body",
+		Spec:        "RFC 9110 §9.3.2",
+		Description: "A response to HEAD must not contain content.",
+		run: func(ctx context.Context, s *Suite) error {
+			c, br, err := s.dial(ctx)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			if _, err := io.WriteString(c, s.rawHead("HEAD", s.path(), "")); err != nil {
+				return err
+			}
+			if _, err := http.ReadResponse(br, &http.Request{Method: "HEAD"}); err != nil {
+				return fmt.Errorf("reading response: %v", err)
+			}
+			// Anything arriving after the header is a body.
+			c.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+			if b, err := br.Peek(1); err == nil {
+				return fmt.Errorf("response to HEAD is followed by data %q", b)
+			}
+			return nil
+		},
+	},
+	{
+		Name:        "expect-100-continue",
+		Spec:        "RFC 9110 §10.1.1",
+		Description: "A request with Expect: 100-continue must receive a 100 (Continue) or final response before its content is sent.",
+		run: func(ctx context.Context, s *Suite) error {
+			c, br, err := s.dial(ctx)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			head := s.rawHead("POST", s.path(), "Expect: 100-continue\r\nContent-Length: 5\r\n")
+			if _, err := io.WriteString(c, head); err != nil {
+				return err
+			}
+			res, err := http.ReadResponse(br, &http.Request{Method: "POST"})
+			if err != nil {
+				return fmt.Errorf("waiting for response before sending content: %v", err)
+			}
+			if res.StatusCode != http.StatusContinue {
+				return nil
+			}
+			if _, err := io.WriteString(c, "hello"); err != nil {
+				return err
+			}
+			if res, err = http.ReadResponse(br, &http.Request{Method: "POST"}); err != nil {
+				return fmt.Errorf("reading final response: %v", err)
+			}
+			if res.StatusCode < 200 {
+				return fmt.Errorf("got status %s after content; want a final response", res.Status)
+			}
+			return nil
+		},
+	},
+	{
+		Name:        "range",
+		Spec:        "RFC 9110 §14.2",
+		Description: "A range request must be answered with 206 (Partial Content) and the requested bytes, or with the full representation.",
+		run: func(ctx context.Context, s *Suite) error {
+			full, err := s.get(ctx, nil)
+			if err != nil {
+				return err
+			}
+			if len(full.body) < 2 {
+				return skipf("resource has %d bytes", len(full.body))
+			}
+			res, err := s.get(ctx, http.Header{"Range": {"bytes=1-1"}})
+			if err != nil {
+				return err
+			}
+			switch res.StatusCode {
+			case http.StatusOK:
+				if hasToken(full.Header.Get("Accept-Ranges"), "bytes") {
+					return errors.New("Accept-Ranges: bytes was sent, but a range request got 200")
+				}
+				return skipf("range requests are not supported")
+			case http.StatusPartialContent:
+			default:
+				return fmt.Errorf("got status %s; want 206 or 200", res.Status)
+			}
+			if got, want := res.Header.Get("Content-Range"), fmt.Sprintf("bytes 1-1/%d", len(full.body)); got != want {
+				return fmt.Errorf("Content-Range = %q; want %q", got, want)
+			}
+			if !bytes.Equal(res.body, full.body[1:2]) {
+				return fmt.Errorf("got content %q; want %q", res.body, full.body[1:2])
+			}
+			return nil
+		},
+	},
+	{
+		Name:        "range-not-satisfiable",
+		Spec:        "RFC 9110 §15.5.17",
+		Description: "A range request none of whose ranges overlaps the representation must be answered with 416 (Range Not Satisfiable) and Content-Range: bytes */length.",
+		run: func(ctx context.Context, s *Suite) error {
+			full, err := s.get(ctx, nil)
+			if err != nil {
+				return err
+			}
+			n := len(full.body)
+			res, err := s.get(ctx, http.Header{"Range": {fmt.Sprintf("bytes=%d-", n+10)}})
+			if err != nil {
+				return err
+			}
+			switch res.StatusCode {
+			case http.StatusOK:
+				return skipf("range requests are not supported")
+			case http.StatusRequestedRangeNotSatisfiable:
+			default:
+				return fmt.Errorf("got status %s; want 416", res.Status)
+			}
+			if got, want := res.Header.Get("Content-Range"), fmt.Sprintf("bytes */%d", n); got != want {
+				return fmt.Errorf("Content-Range = %q; want %q", got, want)
+			}
+			return nil
+		},
+	},
+	{
+		Name:        "if-none-match",
+		Spec:        "RFC 9110 §13.1.2",
+		Description: "A GET with If-None-Match matching the current ETag must be answered with 304 (Not Modified) and no content.",
+		run: func(ctx context.Context, s *Suite) error {
+			return s.expectNotModified(ctx, "ETag", "If-None-Match")
+		},
+	},
+	{
+		Name:        "if-modified-since",
+		Spec:        "RFC 9110 §13.1.3",
+		Description: "A GET with If-Modified-Since equal to Last-Modified must be answered with 304 (Not Modified) and no content.",
+		run: func(ctx context.Context, s *Suite) error {
+			return s.expectNotModified(ctx, "Last-Modified", "If-Modified-Since")
+		},
+	},
+	{
+		Name:        "if-match",
+		Spec:        "RFC 9110 §13.1.1",
+		Description: "A GET with If-Match not matching the current ETag must be answered with 412 (Precondition Failed).",
+		run: func(ctx context.Context, s *Suite) error {
+			full, err := s.get(ctx, nil)
+			if err != nil {
+				return err
+			}
+			if full.Header.Get("ETag") == "" {
+				return skipf("resource has no ETag")
+			}
+			res, err := s.get(ctx, http.Header{"If-Match": {`"conformance-no-such-etag"`}})
+			if err != nil {
+				return err
+			}
+			if res.StatusCode != http.StatusPreconditionFailed {
+				return fmt.Errorf("got status %s; want 412", res.Status)
+			}
+			return nil
+		},
+	},
+}
+
+func (s *Suite) path() string {
+	if s.Path == "" {
+		return "/"
+	}
+	return s.Path
+}
+
+func (s *Suite) url() (*url.URL, error) {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	return u, nil
+}
+
+// dial opens a raw connection to the server, with a deadline from ctx.
+func (s *Suite) dial(ctx context.Context) (net.Conn, *bufio.Reader, error) {
+	if s.Transport != nil {
+		return nil, nil, skipf("the case needs a raw connection, which Transport does not provide")
+	}
+	u, err := s.url()
+	if err != nil {
+		return nil, nil, err
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+	var c net.Conn
+	if u.Scheme == "https" {
+		cfg := s.TLSConfig.Clone()
+		if cfg == nil {
+			cfg = new(tls.Config)
+		}
+		if cfg.ServerName == "" {
+			cfg.ServerName = u.Hostname()
+		}
+		cfg.NextProtos = []string{"http/1.1"}
+		c, err = (&tls.Dialer{Config: cfg}).DialContext(ctx, "tcp", addr)
+	} else {
+		c, err = new(net.Dialer).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if d, ok := ctx.Deadline(); ok {
+		c.SetDeadline(d)
+	}
+	return c, bufio.NewReader(c), nil
+}
+
+// rawHead returns the request line and header of a raw request, with
+// the given extra header lines.
+func (s *Suite) rawHead(method, path, header string) string {
+	u, _ := s.url()
+	host := ""
+	if u != nil {
+		host = u.Host
+	}
+	return method + " " + path + " HTTP/1.1\r\nHost: " + host + "\r\nConnection: close\r\n" + header + "\r\n"
+}
+
+// rawRequest sends a raw request on a new connection and returns the
+// response, with its body read.
+func (s *Suite) rawRequest(ctx context.Context, method, path, header, body string) (*http.Response, error) {
+	c, br, err := s.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	if _, err := io.WriteString(c, s.rawHead(method, path, header)+body); err != nil {
+		return nil, err
+	}
+	res, err := http.ReadResponse(br, &http.Request{Method: method})
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %v", err)
+	}
+	b, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %v", err)
+	}
+	res.Body = io.NopCloser(bytes.NewReader(b))
+	return res, nil
+}
+
+// The halves of the value of the header folded by the obs-fold case.
+const (
+	foldStart = "conformance-fold-start"
+	foldEnd   = "conformance-fold-end"
+)
+
+// checkUnfolded checks that res, a response to the obs-fold case,
+// echoes the folded header's value with the fold replaced by spaces.
+func checkUnfolded(res *http.Response) error {
+	body, _ := io.ReadAll(res.Body)
+	echoes := []string{string(body)}
+	for _, vv := range res.Header {
+		echoes = append(echoes, vv...)
+	}
+	for _, e := range echoes {
+		_, after, ok := strings.Cut(e, foldStart)
+		if !ok {
+			continue
+		}
+		fold, _, ok := strings.Cut(after, foldEnd)
+		if !ok || strings.Trim(fold, " \t") != "" {
+			return fmt.Errorf("folded header echoed as %q; want the fold replaced by spaces", foldStart+fold[:min(len(fold), 32)])
+		}
+		return nil
+	}
+	return skipf("the response does not echo the folded header, so the fold cannot be checked")
+}
+
+func (s *Suite) expectStatus(ctx context.Context, method, header, body string, code int) error {
+	res, err := s.rawRequest(ctx, method, s.path(), header, body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != code {
+		return fmt.Errorf("got status %s; want %d", res.Status, code)
+	}
+	return nil
+}
+
+// A response is a response with its body read.
+type response struct {
+	*http.Response
+	body []byte
+}
+
+// get requests the suite's resource with the given extra header.
+func (s *Suite) get(ctx context.Context, h http.Header) (*response, error) {
+	u, err := s.url()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u.JoinPath(s.path()).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range h {
+		req.Header[k] = v
+	}
+	c := s.Client
+	switch {
+	case s.Transport != nil:
+		c = &http.Client{Transport: s.Transport}
+	case c == nil:
+		c = http.DefaultClient
+	}
+	res, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &response{res, body}, nil
+}
+
+// expectNotModified checks that a GET with the precondition header
+// cond, set to the value of the validator header, gets 304.
+func (s *Suite) expectNotModified(ctx context.Context, validator, cond string) error {
+	full, err := s.get(ctx, nil)
+	if err != nil {
+		return err
+	}
+	v := full.Header.Get(validator)
+	if v == "" {
+		return skipf("resource has no %s", validator)
+	}
+	res, err := s.get(ctx, http.Header{cond: {v}})
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusNotModified {
+		return fmt.Errorf("got status %s with %s: %s; want 304", res.Status, cond, v)
+	}
+	if len(res.body) > 0 {
+		return fmt.Errorf("304 response has content %q", res.body)
+	}
+	return nil
+}
+
+// hasToken reports whether the comma-separated list v contains token,
+// ignoring case.
+func hasToken(v, token string) bool {
+	for t := range strings.SplitSeq(v, ",") {
+		if strings.EqualFold(strings.TrimSpace(t), token) {
+			return true
+		}
+	}
+	return false
+}
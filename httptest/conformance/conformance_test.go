@@ -0,0 +1,213 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conformance
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnsiilver/http/httptest"
+)
+
+func outcomes(results []Result) map[string]Outcome {
+	m := make(map[string]Outcome)
+	for _, r := range results {
+		m[r.Case.Name] = r.Outcome
+	}
+	return m
+}
+
+func checkOutcomes(t *testing.T, results []Result, want map[string]Outcome) {
+	t.Helper()
+	if len(results) != len(Cases) {
+		t.Errorf("got %d results; want %d", len(results), len(Cases))
+	}
+	got := outcomes(results)
+	for _, r := range results {
+		if w, ok := want[r.Case.Name]; ok && r.Outcome != w {
+			t.Errorf("%v; want %v", r, w)
+		}
+	}
+	for name := range want {
+		if _, ok := got[name]; !ok {
+			t.Errorf("no result for case %q", name)
+		}
+	}
+}
+
+func TestServeContent(t *testing.T) {
+	modtime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "hello.txt", modtime, strings.NewReader("hello, world\n"))
+	})
+	want := make(map[string]Outcome)
+	for _, c := range Cases {
+		want[c.Name] = Pass
+	}
+	want["obs-fold"] = Skip // the response does not echo the header
+	checkOutcomes(t, RunHandler(h), want)
+}
+
+func TestPlainHandler(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello, world\n")
+	})
+	checkOutcomes(t, RunHandler(h), map[string]Outcome{
+		"duplicate-content-length": Pass,
+		"obs-fold":                 Skip,
+		"invalid-method":           Pass,
+		"head-no-body":             Pass,
+		"expect-100-continue":      Pass,
+		"range":                    Skip,
+		"range-not-satisfiable":    Skip,
+		"if-none-match":            Skip,
+		"if-modified-since":        Skip,
+		"if-match":                 Skip,
+	})
+}
+
+// serveRaw serves connections with a server that reads the header
+// block of a request and replies with respond(head), closing the
+// connection. It returns the server's URL.
+func serveRaw(t *testing.T, respond func(head string) string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				br := bufio.NewReader(c)
+				var head strings.Builder
+				for {
+					line, err := br.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == "\r\n" {
+						break
+					}
+					head.WriteString(line)
+				}
+				io.WriteString(c, respond(head.String()))
+			}()
+		}
+	}()
+	return "http://" + ln.Addr().String()
+}
+
+// TestNonconformingServer runs the suite against a server that sends
+// the same response to every request.
+func TestNonconformingServer(t *testing.T) {
+	url := serveRaw(t, func(string) string {
+		return "HTTP/1.1 200 OK\r\nAccept-Ranges: bytes\r\nContent-Length: 2\r\nConnection: close\r\n\r\nhi"
+	})
+	s := &Suite{URL: url, Timeout: time.Second}
+	checkOutcomes(t, s.Run(context.Background()), map[string]Outcome{
+		"duplicate-content-length": Fail,
+		"obs-fold":                 Skip,
+		"invalid-method":           Fail,
+		"head-no-body":             Fail,
+		"expect-100-continue":      Pass,
+		"range":                    Fail,
+		"range-not-satisfiable":    Skip,
+		"if-none-match":            Skip,
+	})
+}
+
+func TestObsFold(t *testing.T) {
+	var obsFold *Case
+	for _, c := range Cases {
+		if c.Name == "obs-fold" {
+			obsFold = c
+		}
+	}
+	run := func(url string) Outcome {
+		s := &Suite{URL: url, Timeout: time.Second, Cases: []*Case{obsFold}}
+		return s.Run(context.Background())[0].Outcome
+	}
+	echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("X-Folded"))
+	}))
+	defer echo.Close()
+	// rawEcho echoes the request's header block, fold and all.
+	rawEcho := func(head string) string {
+		return fmt.Sprintf("HTTP/1.1 200 OK\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s", len(head), head)
+	}
+
+	for _, tt := range []struct {
+		name string
+		url  string
+		want Outcome
+	}{
+		{"rejected", serveRaw(t, func(string) string {
+			return "HTTP/1.1 400 Bad Request\r\nContent-Length: 0\r\nConnection: close\r\n\r\n"
+		}), Pass},
+		{"unfolded echo", echo.URL, Pass},
+		{"raw echo", serveRaw(t, rawEcho), Fail},
+		{"not found", serveRaw(t, func(string) string {
+			return "HTTP/1.1 404 Not Found\r\nContent-Length: 0\r\nConnection: close\r\n\r\n"
+		}), Fail},
+	} {
+		if got := run(tt.url); got != tt.want {
+			t.Errorf("%s: obs-fold outcome = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// handlerTransport is a RoundTripper that serves requests with a
+// handler, without a network connection.
+type handlerTransport struct {
+	h http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	t.h.ServeHTTP(rec, req)
+	res := rec.Result()
+	res.Request = req
+	return res, nil
+}
+
+func TestTransport(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "hello.txt", time.Time{}, strings.NewReader("hello, world\n"))
+	})
+	s := &Suite{URL: "http://conformance.invalid", Transport: handlerTransport{h}}
+	checkOutcomes(t, s.Run(context.Background()), map[string]Outcome{
+		"duplicate-content-length": Skip,
+		"obs-fold":                 Skip,
+		"invalid-method":           Skip,
+		"head-no-body":             Skip,
+		"expect-100-continue":      Skip,
+		"range":                    Pass,
+		"range-not-satisfiable":    Pass,
+		"if-none-match":            Pass,
+		"if-match":                 Pass,
+	})
+}
+
+func TestSuiteTest(t *testing.T) {
+	// The suite's own subtests should pass against a conforming handler.
+	TestHandler(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader("data"))
+	}))
+}
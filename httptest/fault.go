@@ -0,0 +1,418 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Fault injection for Server
+
+package httptest
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A Fault describes misbehavior injected by a [Server] into the
+// connections it serves, for testing how clients cope with slow,
+// broken or overloaded servers.
+//
+// On plain servers, faults act on the bytes written to the
+// connection, so sizes count the bytes of the body as sent, including
+// any chunked framing. On TLS servers, faults act on the response
+// before it is encrypted, so sizes count the bytes of the body as the
+// handler writes them. Except for DropOnAccept, a fault is chosen at
+// the start of each request and applies to the response written for
+// it; resets and truncations close the whole connection, and on
+// plain HTTP/2 connections, which carry many requests at once, all
+// faults affect the whole connection.
+//
+// All waits use ordinary timers, so faults behave deterministically
+// when the server and its clients run under fake time.
+type Fault struct {
+	// Match, if non-nil, restricts the fault to requests for
+	// which it reports true. A nil Match applies to all requests.
+	Match func(r *http.Request) bool
+
+	// Latency delays the first byte of the response.
+	Latency time.Duration
+
+	// Bandwidth, if positive, limits the rate at which the
+	// response is written, in bytes per second.
+	Bandwidth int
+
+	// StallAfterHeaders pauses the response after its header
+	// block has been written, before any body is sent.
+	StallAfterHeaders time.Duration
+
+	// ResetAfter, if positive, aborts the connection with a TCP
+	// reset once that many bytes of the response following its
+	// header block have been written.
+	ResetAfter int64
+
+	// TruncateAfter, if positive, closes the connection normally
+	// once that many bytes of the response following its header
+	// block have been written.
+	TruncateAfter int64
+
+	// DropOnAccept closes connections as soon as they are
+	// accepted, before any request is read. Match is not consulted;
+	// a Fault with DropOnAccept set and a non-nil Match has no
+	// effect on accepting.
+	DropOnAccept bool
+}
+
+// errFaultInjected is returned by writes to a connection whose
+// response was cut short by a Fault.
+var errFaultInjected = errors.New("httptest: connection closed by injected fault")
+
+// SetFaults replaces the faults injected by s. It may be called at
+// any time, and applies to requests starting afterwards. Fault
+// injection must have been enabled by setting the Faults field before
+// the server was started; otherwise SetFaults panics.
+func (s *Server) SetFaults(faults ...Fault) {
+	if s.URL == "" {
+		s.Faults = faults
+		return
+	}
+	s.faultMu.Lock()
+	defer s.faultMu.Unlock()
+	if !s.faultsOn {
+		panic("httptest: SetFaults on a Server started without Faults")
+	}
+	s.faults = append([]Fault(nil), faults...)
+}
+
+// wrapFaults enables fault injection if s.Faults is set, by wrapping
+// s.Listener. It must be called before any TLS listener is layered
+// on top, as faultHandler finds the faultConn beneath a *tls.Conn.
+func (s *Server) wrapFaults() {
+	if s.Faults == nil {
+		return
+	}
+	s.faultMu.Lock()
+	s.faultsOn = true
+	s.faults = append([]Fault(nil), s.Faults...)
+	s.faultMu.Unlock()
+	s.Listener = &faultListener{Listener: s.Listener, s: s}
+}
+
+// faultFor returns the fault to apply to r, or nil.
+func (s *Server) faultFor(r *http.Request) *Fault {
+	s.faultMu.Lock()
+	defer s.faultMu.Unlock()
+	for i := range s.faults {
+		f := &s.faults[i]
+		if f.Match == nil || f.Match(r) {
+			fc := *f
+			return &fc
+		}
+	}
+	return nil
+}
+
+func (s *Server) dropOnAccept() bool {
+	s.faultMu.Lock()
+	defer s.faultMu.Unlock()
+	for _, f := range s.faults {
+		if f.DropOnAccept && f.Match == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// faultHandler returns a handler that selects the fault for each
+// request before passing it to h.
+func (s *Server) faultHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch c := connFromContext(r.Context()).(type) {
+		case *faultConn:
+			c.begin(s.faultFor(r))
+		case *tls.Conn:
+			// The bytes on the connection are encrypted, so inject
+			// the fault into the plaintext response instead.
+			if fc, ok := c.NetConn().(*faultConn); ok {
+				if f := s.faultFor(r); f != nil {
+					w = &faultWriter{ResponseWriter: w, f: f, tc: c, fc: fc}
+				}
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+type faultListener struct {
+	net.Listener
+	s *Server
+}
+
+func (l *faultListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if l.s.dropOnAccept() {
+			c.Close()
+			continue
+		}
+		return &faultConn{Conn: c, closed: make(chan struct{})}, nil
+	}
+}
+
+// A faultConn is a net.Conn that injects a Fault into the response
+// written after each call to begin.
+type faultConn struct {
+	net.Conn
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	mu      sync.Mutex // guards the fields below
+	f       *Fault
+	started bool   // first byte of the response written
+	inBody  bool   // header block written
+	head    []byte // header block written so far
+	written int64  // bytes written after the header block
+}
+
+// begin starts a new response, subject to f if non-nil.
+func (c *faultConn) begin(f *Fault) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.f = f
+	c.started = false
+	c.inBody = false
+	c.head = c.head[:0]
+	c.written = 0
+}
+
+func (c *faultConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return c.Conn.Close()
+}
+
+// CloseWrite forwards to the underlying connection if it supports
+// half-closing, as the http.Server uses it when shutting down
+// connections gracefully.
+func (c *faultConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+// reset closes the connection, discarding unsent data and sending a
+// TCP reset where supported.
+func (c *faultConn) reset() {
+	if lc, ok := c.Conn.(interface{ SetLinger(int) error }); ok {
+		lc.SetLinger(0)
+	}
+	c.Close()
+}
+
+// sleep waits for d, returning false if the connection is closed
+// first.
+func (c *faultConn) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-c.closed:
+		return false
+	}
+}
+
+func (c *faultConn) Write(p []byte) (n int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f := c.f
+	if f == nil {
+		return c.Conn.Write(p)
+	}
+	if !c.started {
+		c.started = true
+		if !c.sleep(f.Latency) {
+			return 0, net.ErrClosed
+		}
+	}
+	limit := f.bodyLimit()
+	for len(p) > 0 {
+		chunk := p
+		if !c.inBody {
+			if i := c.headerEnd(p); i >= 0 {
+				chunk = p[:i]
+			}
+		} else if limit > 0 {
+			if c.written >= limit {
+				return n, errFaultInjected
+			}
+			chunk = chunk[:min(int64(len(chunk)), limit-c.written)]
+		}
+		if f.Bandwidth > 0 {
+			// Write in slices of about a tenth of a second, each
+			// after the time it would take to transmit.
+			chunk = chunk[:min(len(chunk), max(f.Bandwidth/10, 1))]
+			if !c.sleep(time.Duration(len(chunk)) * time.Second / time.Duration(f.Bandwidth)) {
+				return n, net.ErrClosed
+			}
+		}
+
+		m, err := c.Conn.Write(chunk)
+		n += m
+		p = p[m:]
+		if err != nil {
+			return n, err
+		}
+		if c.inBody {
+			c.written += int64(m)
+		} else {
+			c.scanHeader(chunk[:m])
+			if c.inBody && !c.sleep(f.StallAfterHeaders) {
+				return n, net.ErrClosed
+			}
+		}
+		if c.inBody && limit > 0 && c.written >= limit {
+			if f.ResetAfter == limit {
+				c.reset()
+			} else {
+				c.Close()
+			}
+			if len(p) > 0 {
+				return n, errFaultInjected
+			}
+		}
+	}
+	return n, nil
+}
+
+// headerEnd returns the length of the prefix of p that completes the
+// current header block, or -1 if p does not complete it.
+func (c *faultConn) headerEnd(p []byte) int {
+	tail := c.head[max(len(c.head)-3, 0):]
+	buf := append(append([]byte(nil), tail...), p...)
+	i := bytes.Index(buf, []byte("\r\n\r\n"))
+	if i < 0 {
+		return -1
+	}
+	return i + 4 - len(tail)
+}
+
+// scanHeader records b, written as part of a header block, and notes
+// when the final header block is complete. Interim 1xx responses,
+// such as 100 Continue, do not count as the final header block.
+func (c *faultConn) scanHeader(b []byte) {
+	c.head = append(c.head, b...)
+	if !bytes.HasSuffix(c.head, []byte("\r\n\r\n")) {
+		return
+	}
+	// "HTTP/1.1 1xx ..."
+	interim := len(c.head) > len("HTTP/1.1 1") && c.head[len("HTTP/1.1 ")] == '1'
+	c.head = c.head[:0]
+	c.inBody = !interim
+}
+
+// A faultWriter injects a Fault into a response on a TLS connection,
+// where faultConn, beneath the TLS layer, cannot see the header block.
+type faultWriter struct {
+	http.ResponseWriter
+	f  *Fault
+	tc *tls.Conn  // connection the response is written to
+	fc *faultConn // raw connection beneath tc
+
+	started     bool  // first byte of the response written
+	wroteHeader bool  // final header block written
+	written     int64 // bytes of the body written
+}
+
+// start delays the first byte of the response.
+func (w *faultWriter) start() bool {
+	if w.started {
+		return true
+	}
+	w.started = true
+	return w.fc.sleep(w.f.Latency)
+}
+
+func (w *faultWriter) WriteHeader(code int) {
+	if !w.start() {
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+	if code < 200 || w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if w.f.StallAfterHeaders > 0 {
+		http.NewResponseController(w.ResponseWriter).Flush()
+		w.fc.sleep(w.f.StallAfterHeaders)
+	}
+}
+
+func (w *faultWriter) Write(p []byte) (n int, err error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	limit := w.f.bodyLimit()
+	for len(p) > 0 {
+		if limit > 0 && w.written >= limit {
+			return n, errFaultInjected
+		}
+		chunk := p
+		if limit > 0 {
+			chunk = chunk[:min(int64(len(chunk)), limit-w.written)]
+		}
+		if w.f.Bandwidth > 0 {
+			chunk = chunk[:min(len(chunk), max(w.f.Bandwidth/10, 1))]
+			if !w.fc.sleep(time.Duration(len(chunk)) * time.Second / time.Duration(w.f.Bandwidth)) {
+				return n, net.ErrClosed
+			}
+		}
+		m, err := w.ResponseWriter.Write(chunk)
+		n += m
+		w.written += int64(m)
+		p = p[m:]
+		if err != nil {
+			return n, err
+		}
+		if w.f.Bandwidth > 0 || limit > 0 && w.written >= limit {
+			http.NewResponseController(w.ResponseWriter).Flush()
+		}
+		if limit > 0 && w.written >= limit {
+			if w.f.ResetAfter == limit {
+				w.fc.reset()
+			} else {
+				w.tc.Close()
+			}
+		}
+	}
+	return n, nil
+}
+
+func (w *faultWriter) FlushError() error {
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *faultWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// bodyLimit returns the number of bytes after the header block after
+// which f cuts the response short, or 0 for none.
+func (f *Fault) bodyLimit() int64 {
+	switch {
+	case f.ResetAfter > 0 && f.TruncateAfter > 0:
+		return min(f.ResetAfter, f.TruncateAfter)
+	case f.ResetAfter > 0:
+		return f.ResetAfter
+	default:
+		return max(f.TruncateAfter, 0)
+	}
+}
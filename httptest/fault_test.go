@@ -0,0 +1,134 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httptest
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFaultServer(t *testing.T, faults ...Fault) *Server {
+	t.Helper()
+	return startFaultServer(t, (*Server).Start, faults...)
+}
+
+func startFaultServer(t *testing.T, start func(*Server), faults ...Fault) *Server {
+	t.Helper()
+	ts := NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		io.WriteString(w, strings.Repeat("x", 1000))
+	}))
+	ts.SetFaults(faults...)
+	start(ts)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func getBody(ts *Server, path string) (string, error) {
+	res, err := ts.Client().Get(ts.URL + path)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	return string(b), err
+}
+
+func TestFaultTruncateAndReset(t *testing.T) {
+	for _, f := range []Fault{{TruncateAfter: 10}, {ResetAfter: 10}} {
+		ts := newFaultServer(t, f)
+		body, err := getBody(ts, "/")
+		if err == nil {
+			t.Errorf("%+v: read succeeded; want error", f)
+		}
+		if len(body) != 10 {
+			t.Errorf("%+v: read %d bytes; want 10", f, len(body))
+		}
+	}
+}
+
+func TestFaultDelays(t *testing.T) {
+	const d = 50 * time.Millisecond
+	for _, f := range []Fault{
+		{Latency: d},
+		{StallAfterHeaders: d},
+		{Bandwidth: 1000 * int(time.Second/d)},
+	} {
+		ts := newFaultServer(t, f)
+		start := time.Now()
+		if _, err := getBody(ts, "/"); err != nil {
+			t.Fatalf("%+v: %v", f, err)
+		}
+		if elapsed := time.Since(start); elapsed < d {
+			t.Errorf("%+v: request took %v; want at least %v", f, elapsed, d)
+		}
+	}
+}
+
+func TestFaultTLS(t *testing.T) {
+	for _, f := range []Fault{{TruncateAfter: 10}, {ResetAfter: 10}} {
+		ts := startFaultServer(t, (*Server).StartTLS, f)
+		body, err := getBody(ts, "/")
+		if err == nil {
+			t.Errorf("%+v: read succeeded; want error", f)
+		}
+		if len(body) != 10 {
+			t.Errorf("%+v: read %d bytes; want 10", f, len(body))
+		}
+	}
+
+	const d = 50 * time.Millisecond
+	for _, f := range []Fault{
+		{Latency: d},
+		{StallAfterHeaders: d},
+		{Bandwidth: 1000 * int(time.Second/d)},
+	} {
+		ts := startFaultServer(t, (*Server).StartTLS, f)
+		start := time.Now()
+		if _, err := getBody(ts, "/"); err != nil {
+			t.Fatalf("%+v: %v", f, err)
+		}
+		if elapsed := time.Since(start); elapsed < d {
+			t.Errorf("%+v: request took %v; want at least %v", f, elapsed, d)
+		}
+	}
+}
+
+func TestFaultMatchAndSetFaults(t *testing.T) {
+	ts := newFaultServer(t, Fault{
+		Match:         func(r *http.Request) bool { return r.URL.Path == "/broken" },
+		TruncateAfter: 1,
+	})
+	if _, err := getBody(ts, "/ok"); err != nil {
+		t.Errorf("unmatched request failed: %v", err)
+	}
+	if _, err := getBody(ts, "/broken"); err == nil {
+		t.Errorf("matched request succeeded; want error")
+	}
+
+	ts.SetFaults(Fault{DropOnAccept: true})
+	ts.CloseClientConnections()
+	if _, err := getBody(ts, "/ok"); err == nil {
+		t.Errorf("request with DropOnAccept succeeded; want error")
+	}
+	ts.SetFaults()
+	if _, err := getBody(ts, "/broken"); err != nil {
+		t.Errorf("request after clearing faults failed: %v", err)
+	}
+}
+
+func TestSetFaultsWithoutFaults(t *testing.T) {
+	ts := NewServer(http.NotFoundHandler())
+	defer ts.Close()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("SetFaults on server started without Faults did not panic")
+		}
+	}()
+	ts.SetFaults(Fault{Latency: time.Second})
+}
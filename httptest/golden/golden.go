@@ -0,0 +1,303 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package golden compares HTTP exchanges against golden files.
+//
+// A golden test runs a handler against a request and compares the
+// dumped request and response with a file under testdata, after
+// replacing values that change from run to run, such as the Date
+// header. When the handler's output changes intentionally, running
+//
+//	go test -golden.update
+//
+// rewrites the golden files with the new output. Otherwise a
+// mismatch fails the test with a unified diff. Tests with their own
+// flag for updating golden files can set [Golden.Update] from it.
+package golden
+
+import (
+	"bytes"
+	"cmp"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/johnsiilver/http/httptest"
+	"github.com/johnsiilver/http/httputil"
+)
+
+// update is the -golden.update flag, namespaced so as not to clash
+// with flags of the test binary.
+var update = flag.Bool("golden.update", false, "rewrite golden files instead of comparing against them")
+
+// DefaultVolatileHeaders lists the headers whose values a [Golden]
+// replaces with [Placeholder] by default.
+var DefaultVolatileHeaders = []string{
+	"Date",
+	"Request-Id",
+	"Traceparent",
+	"X-Correlation-Id",
+	"X-Request-Id",
+}
+
+// Placeholder is the value that replaces volatile header values.
+const Placeholder = "<volatile>"
+
+// A Replacement replaces each match of Regexp in a dumped exchange
+// with With, which may refer to submatches as in
+// [regexp.Regexp.ReplaceAllString].
+type Replacement struct {
+	Regexp *regexp.Regexp
+	With   string
+}
+
+// A Golden configures golden-file comparisons. The zero value is
+// ready to use, as are the package-level functions, which use it.
+type Golden struct {
+	// Dir is the directory holding golden files.
+	// If empty, "testdata" is used.
+	Dir string
+
+	// VolatileHeaders lists headers, in addition to
+	// DefaultVolatileHeaders, whose values are replaced with
+	// Placeholder in requests and responses.
+	VolatileHeaders []string
+
+	// Replace lists replacements applied, in order, to the dumped
+	// exchange after volatile headers are replaced.
+	Replace []Replacement
+
+	// Update rewrites golden files instead of comparing against
+	// them, as the -golden.update flag does.
+	Update bool
+}
+
+// Handler runs h against req, which is typically created with
+// [httptest.NewRequest], and compares the exchange with the golden
+// file named name+".golden", as for [Golden.Compare].
+func (g *Golden) Handler(t testing.TB, name string, h http.Handler, req *http.Request) {
+	t.Helper()
+	dump, err := g.dumpRequest(req)
+	if err != nil {
+		t.Fatalf("golden: dumping request: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	res := rec.Result()
+	res.Request = req
+	g.compare(t, name, dump, res)
+}
+
+// Compare compares the exchange of res and its Request, if any, with
+// the golden file named name+".golden". If g.Update or the
+// -golden.update flag is set, it rewrites the file instead. The response body is consumed.
+func (g *Golden) Compare(t testing.TB, name string, res *http.Response) {
+	t.Helper()
+	var dump []byte
+	if res.Request != nil {
+		var err error
+		if dump, err = g.dumpRequest(res.Request); err != nil {
+			t.Fatalf("golden: dumping request: %v", err)
+		}
+	}
+	g.compare(t, name, dump, res)
+}
+
+func (g *Golden) compare(t testing.TB, name string, reqDump []byte, res *http.Response) {
+	t.Helper()
+	defer res.Body.Close()
+	g.normalizeHeader(res.Header)
+	resDump, err := httputil.DumpResponse(res, true)
+	if err != nil {
+		t.Fatalf("golden: dumping response: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if reqDump != nil {
+		buf.WriteString("--- request\n")
+		buf.Write(crlfToLF(reqDump))
+		buf.WriteString("\n")
+	}
+	buf.WriteString("--- response\n")
+	buf.Write(crlfToLF(resDump))
+	got := buf.String()
+	for _, r := range g.Replace {
+		got = r.Regexp.ReplaceAllString(got, r.With)
+	}
+
+	file := filepath.Join(cmp.Or(g.Dir, "testdata"), name+".golden")
+	if g.Update || *update {
+		if err := os.MkdirAll(filepath.Dir(file), 0o777); err != nil {
+			t.Fatalf("golden: %v", err)
+		}
+		if err := os.WriteFile(file, []byte(got), 0o666); err != nil {
+			t.Fatalf("golden: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("golden: %v (run with -golden.update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("golden: exchange differs from %s (run with -golden.update to accept it):\n%s", file, unifiedDiff(file, "got", string(want), got))
+	}
+}
+
+// dumpRequest returns the dump of req with volatile headers replaced.
+// It leaves req's header unchanged.
+func (g *Golden) dumpRequest(req *http.Request) ([]byte, error) {
+	h := req.Header
+	req.Header = h.Clone()
+	defer func() { req.Header = h }()
+	g.normalizeHeader(req.Header)
+	return httputil.DumpRequest(req, true)
+}
+
+func (g *Golden) normalizeHeader(h http.Header) {
+	for _, lists := range [][]string{DefaultVolatileHeaders, g.VolatileHeaders} {
+		for _, k := range lists {
+			k = http.CanonicalHeaderKey(k)
+			for i := range h[k] {
+				h[k][i] = Placeholder
+			}
+		}
+	}
+}
+
+// crlfToLF converts the line endings of a dump to newlines, keeping
+// golden files readable and editable.
+func crlfToLF(b []byte) []byte {
+	return bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+}
+
+// Handler calls [Golden.Handler] on a zero Golden.
+func Handler(t testing.TB, name string, h http.Handler, req *http.Request) {
+	t.Helper()
+	new(Golden).Handler(t, name, h, req)
+}
+
+// Compare calls [Golden.Compare] on a zero Golden.
+func Compare(t testing.TB, name string, res *http.Response) {
+	t.Helper()
+	new(Golden).Compare(t, name, res)
+}
+
+// unifiedDiff returns a unified diff of the lines of a and b, with
+// three lines of context.
+func unifiedDiff(aName, bName, a, b string) string {
+	al, bl := splitLines(a), splitLines(b)
+	ops := diffLines(al, bl)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+	const context = 3
+	for i := 0; i < len(ops); {
+		// Find the next change.
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		start := max(i-context, 0)
+		// Extend the hunk while changes are within 2*context lines.
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end >= 2*context {
+				break
+			}
+		}
+		end = min(end+context, len(ops))
+
+		ai, bi := ops[start].a, ops[start].b
+		var an, bn int
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				an++
+			}
+			if op.kind != '-' {
+				bn++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", ai+1, an, bi+1, bn)
+		for _, op := range ops[start:end] {
+			var line string
+			if op.kind == '+' {
+				line = bl[op.b]
+			} else {
+				line = al[op.a]
+			}
+			fmt.Fprintf(&out, "%c%s\n", op.kind, line)
+		}
+		i = end
+	}
+	return out.String()
+}
+
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i, l := range lines {
+		if strings.HasSuffix(l, "\n") {
+			lines[i] = l[:len(l)-1]
+		} else {
+			lines[i] = l + " (no newline at end)"
+		}
+	}
+	return lines
+}
+
+// A diffOp is one line of a diff: kind is ' ' for a line common to
+// both inputs, '-' for a line only in a and '+' for one only in b.
+// a and b are the line's position in each input.
+type diffOp struct {
+	kind byte
+	a, b int
+}
+
+// diffLines returns an edit script turning a into b, computed from
+// their longest common subsequence.
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', i, j})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', i, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', i, j})
+			j++
+		}
+	}
+	return ops
+}
@@ -0,0 +1,131 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golden
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnsiilver/http/httptest"
+)
+
+// fakeTB records the errors reported by a comparison.
+type fakeTB struct {
+	testing.TB
+	errors []string
+}
+
+func (tb *fakeTB) Helper() {}
+
+func (tb *fakeTB) Errorf(format string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func greet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	w.Header().Set("X-Request-Id", fmt.Sprint(time.Now().UnixNano()))
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	b, _ := io.ReadAll(r.Body)
+	fmt.Fprintf(w, "hello, %s at %s\n", b, time.Now().UTC().Format(time.RFC3339))
+}
+
+func newGolden() *Golden {
+	return &Golden{
+		VolatileHeaders: []string{"X-Session"},
+		Replace: []Replacement{
+			{regexp.MustCompile(`at \d{4}-\d\d-\d\dT[^\s]+`), "at <time>"},
+		},
+	}
+}
+
+func TestHandler(t *testing.T) {
+	req := httptest.NewRequest("POST", "/greet", strings.NewReader("gopher"))
+	req.Header.Set("X-Session", "abc123")
+	newGolden().Handler(t, "greet", http.HandlerFunc(greet), req)
+	if got := req.Header.Get("X-Session"); got != "abc123" {
+		t.Errorf("request header X-Session = %q after comparison; want unchanged", got)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(greet))
+	defer ts.Close()
+	res, err := ts.Client().Post(ts.URL+"/greet", "text/plain", strings.NewReader("gopher"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The request made by the client varies with the server's address.
+	res.Request = nil
+	newGolden().Compare(t, "greet_response", res)
+}
+
+func TestMismatch(t *testing.T) {
+	tb := &fakeTB{TB: t}
+	req := httptest.NewRequest("POST", "/greet", strings.NewReader("world"))
+	req.Header.Set("X-Session", "abc123")
+	newGolden().Handler(tb, "greet", http.HandlerFunc(greet), req)
+	if len(tb.errors) != 1 {
+		t.Fatalf("got %d errors; want 1: %q", len(tb.errors), tb.errors)
+	}
+	msg := tb.errors[0]
+	for _, want := range []string{
+		"--- testdata/greet.golden\n+++ got\n",
+		"\n-gopher\n+world\n",
+		"\n-hello, gopher at <time>\n+hello, world at <time>\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error does not contain %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	g := newGolden()
+	g.Dir = t.TempDir()
+	g.Update = true
+	req := httptest.NewRequest("POST", "/greet", strings.NewReader("gopher"))
+	g.Handler(t, "greet", http.HandlerFunc(greet), req)
+
+	tb := &fakeTB{TB: t}
+	g.Update = false
+	req = httptest.NewRequest("POST", "/greet", strings.NewReader("gopher"))
+	g.Handler(tb, "greet", http.HandlerFunc(greet), req)
+	if len(tb.errors) != 0 {
+		t.Errorf("comparison with the updated file failed: %q", tb.errors)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "1\n2\n3\nfour\n5\n6\n7\n8\n9\n10\n11\n"
+	want := `--- a
++++ b
+@@ -1,7 +1,7 @@
+ 1
+ 2
+ 3
+-4
++four
+ 5
+ 6
+ 7
+@@ -9,4 +9,3 @@
+ 9
+ 10
+ 11
+-12
+`
+	if got := unifiedDiff("a", "b", a, b); got != want {
+		t.Errorf("unifiedDiff:\n%s\nwant:\n%s", got, want)
+	}
+	if got := unifiedDiff("a", "b", "x\n", "x"); !strings.Contains(got, "+x (no newline at end)") {
+		t.Errorf("unifiedDiff of missing final newline:\n%s", got)
+	}
+}
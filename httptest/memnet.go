@@ -0,0 +1,323 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// In-memory network for Server
+
+package httptest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A MemListener is a [net.Listener] for an in-memory network: each
+// call to its Dial or DialContext method creates a connected pair of
+// in-memory connections, one returned to the dialer and the other by
+// Accept. The connections support deadlines and half-closing with
+// CloseWrite and CloseRead, like TCP connections, and buffer a
+// limited amount of data in each direction.
+//
+// No kernel network resources are used, so tests using a MemListener
+// are not limited by the number of available ports, and they block
+// only on channels and timers, which allows them to run under fake
+// time.
+type MemListener struct {
+	addr      memAddr
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var (
+	memListenerID atomic.Int64
+	memDialID     atomic.Int64 // numbers the client ends of connections
+)
+
+// NewMemListener returns a new MemListener.
+//
+// Its address has the form "memoryN.invalid:80". The host name cannot
+// be resolved, so a request for the URL of a [Server] using the
+// listener fails unless it is made with the Server's Client or
+// another client dialing the listener.
+func NewMemListener() *MemListener {
+	id := memListenerID.Add(1)
+	return &MemListener{
+		addr:  memAddr("memory" + strconv.FormatInt(id, 10) + ".invalid:80"),
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+// Accept waits for and returns the next connection dialed to l.
+func (l *MemListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.done:
+		return nil, &net.OpError{Op: "accept", Net: l.addr.Network(), Addr: l.addr, Err: net.ErrClosed}
+	}
+}
+
+// Close closes l. Connections already accepted are not affected.
+func (l *MemListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return nil
+}
+
+// Addr returns l's address.
+func (l *MemListener) Addr() net.Addr { return l.addr }
+
+// errConnRefused is returned when dialing a closed MemListener.
+var errConnRefused = errors.New("connection refused")
+
+// Dial connects to l.
+func (l *MemListener) Dial() (net.Conn, error) {
+	return l.DialContext(context.Background(), l.addr.Network(), l.addr.String())
+}
+
+// DialContext connects to l, regardless of network and address. It
+// has the signature of [http.Transport.DialContext], to which it may
+// be assigned. DialContext blocks until the connection is accepted,
+// ctx is done or l is closed.
+func (l *MemListener) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	caddr := memAddr("client.invalid:" + strconv.FormatInt(memDialID.Add(1), 10))
+	client, server := newMemConnPair(caddr, l.addr)
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.done:
+		return nil, &net.OpError{Op: "dial", Net: l.addr.Network(), Addr: l.addr, Err: errConnRefused}
+	case <-ctx.Done():
+		return nil, &net.OpError{Op: "dial", Net: l.addr.Network(), Addr: l.addr, Err: ctx.Err()}
+	}
+}
+
+type memAddr string
+
+func (memAddr) Network() string  { return "memory" }
+func (a memAddr) String() string { return string(a) }
+
+// NewMemServer starts and returns a new [Server] listening on a
+// [MemListener]. Its Client dials the listener directly, so the
+// server and client run without the kernel network stack.
+// The caller should call Close when finished, to shut it down.
+//
+// To use TLS or otherwise configure the server before it starts,
+// set the Listener of a Server returned by [NewUnstartedServer] to a
+// MemListener instead; Start and StartTLS configure the Server's
+// Client for any MemListener.
+func NewMemServer(handler http.Handler) *Server {
+	ts := &Server{
+		Listener: NewMemListener(),
+		Config:   &http.Server{Handler: handler},
+	}
+	ts.Start()
+	return ts
+}
+
+// dialMem configures t to dial s's listener if it is a MemListener.
+func (s *Server) dialMem(t *http.Transport) {
+	if ml := s.memListener; ml != nil {
+		t.DialContext = ml.DialContext
+		if t.TLSClientConfig != nil {
+			// The listener's address is not a name in the test
+			// certificate.
+			t.TLSClientConfig.ServerName = "example.com"
+		}
+	}
+}
+
+// memPipeSize is the number of bytes a memPipe buffers.
+const memPipeSize = 64 << 10
+
+// A memPipe is one direction of an in-memory connection.
+type memPipe struct {
+	mu            sync.Mutex
+	buf           []byte
+	readClosed    bool // reading end closed
+	writeClosed   bool // writing end closed
+	readDeadline  time.Time
+	writeDeadline time.Time
+	changed       chan struct{} // closed and replaced when state changes
+}
+
+// notifyLocked wakes goroutines waiting for p to change.
+// p.mu must be held.
+func (p *memPipe) notifyLocked() {
+	if p.changed != nil {
+		close(p.changed)
+		p.changed = nil
+	}
+}
+
+// waitLocked waits until p changes or deadline passes. It returns
+// os.ErrDeadlineExceeded if deadline has already passed.
+// p.mu must be held; it is released while waiting.
+func (p *memPipe) waitLocked(deadline time.Time) error {
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		d := time.Until(deadline)
+		if d <= 0 {
+			return os.ErrDeadlineExceeded
+		}
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timeout = t.C
+	}
+	if p.changed == nil {
+		p.changed = make(chan struct{})
+	}
+	changed := p.changed
+	p.mu.Unlock()
+	select {
+	case <-changed:
+	case <-timeout:
+	}
+	p.mu.Lock()
+	return nil
+}
+
+func (p *memPipe) read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for {
+		switch {
+		case p.readClosed:
+			return 0, net.ErrClosed
+		case !p.readDeadline.IsZero() && !time.Now().Before(p.readDeadline):
+			return 0, os.ErrDeadlineExceeded
+		case len(p.buf) > 0:
+			n := copy(b, p.buf)
+			p.buf = p.buf[n:]
+			p.notifyLocked()
+			return n, nil
+		case p.writeClosed:
+			return 0, io.EOF
+		}
+		if err := p.waitLocked(p.readDeadline); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (p *memPipe) write(b []byte) (n int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for {
+		switch {
+		case p.writeClosed:
+			return n, net.ErrClosed
+		case p.readClosed:
+			return n, io.ErrClosedPipe
+		case len(b) == 0:
+			return n, nil
+		case !p.writeDeadline.IsZero() && !time.Now().Before(p.writeDeadline):
+			return n, os.ErrDeadlineExceeded
+		case len(p.buf) < memPipeSize:
+			m := min(len(b), memPipeSize-len(p.buf))
+			p.buf = append(p.buf, b[:m]...)
+			b = b[m:]
+			n += m
+			p.notifyLocked()
+			continue
+		}
+		if err := p.waitLocked(p.writeDeadline); err != nil {
+			return n, err
+		}
+	}
+}
+
+func (p *memPipe) closeRead() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.readClosed = true
+	p.buf = nil
+	p.notifyLocked()
+}
+
+func (p *memPipe) closeWrite() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.writeClosed = true
+	p.notifyLocked()
+}
+
+func (p *memPipe) setReadDeadline(t time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.readDeadline = t
+	p.notifyLocked()
+}
+
+func (p *memPipe) setWriteDeadline(t time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.writeDeadline = t
+	p.notifyLocked()
+}
+
+// newMemConnPair returns the two ends of a new in-memory connection
+// between the addresses a and b.
+func newMemConnPair(a, b net.Addr) (*memConn, *memConn) {
+	a2b, b2a := new(memPipe), new(memPipe)
+	return &memConn{rd: b2a, wr: a2b, local: a, remote: b},
+		&memConn{rd: a2b, wr: b2a, local: b, remote: a}
+}
+
+// A memConn is one end of an in-memory connection.
+type memConn struct {
+	rd, wr        *memPipe
+	local, remote net.Addr
+}
+
+func (c *memConn) Read(b []byte) (int, error)  { return c.rd.read(b) }
+func (c *memConn) Write(b []byte) (int, error) { return c.wr.write(b) }
+
+// Close closes both directions of the connection. Data not yet read
+// by the peer is still delivered.
+func (c *memConn) Close() error {
+	c.rd.closeRead()
+	c.wr.closeWrite()
+	return nil
+}
+
+// CloseRead shuts down the reading side of the connection.
+func (c *memConn) CloseRead() error {
+	c.rd.closeRead()
+	return nil
+}
+
+// CloseWrite shuts down the writing side of the connection; the peer
+// reads io.EOF once it has read the data already written.
+func (c *memConn) CloseWrite() error {
+	c.wr.closeWrite()
+	return nil
+}
+
+func (c *memConn) LocalAddr() net.Addr  { return c.local }
+func (c *memConn) RemoteAddr() net.Addr { return c.remote }
+
+func (c *memConn) SetDeadline(t time.Time) error {
+	c.rd.setReadDeadline(t)
+	c.wr.setWriteDeadline(t)
+	return nil
+}
+
+func (c *memConn) SetReadDeadline(t time.Time) error {
+	c.rd.setReadDeadline(t)
+	return nil
+}
+
+func (c *memConn) SetWriteDeadline(t time.Time) error {
+	c.wr.setWriteDeadline(t)
+	return nil
+}
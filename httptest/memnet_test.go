@@ -0,0 +1,117 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httptest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemServer(t *testing.T) {
+	ts := NewMemServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Write(b)
+	}))
+	defer ts.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body := strings.Repeat("x", 100<<10) // larger than the pipe buffer
+			res, err := ts.Client().Post(ts.URL, "text/plain", strings.NewReader(body))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			b, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil || string(b) != body {
+				t.Errorf("echoed %d bytes, %v; want %d bytes", len(b), err, len(body))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := http.Get(ts.URL); err == nil {
+		t.Errorf("request with default client succeeded; want error")
+	}
+}
+
+func TestMemServerTLS(t *testing.T) {
+	ts := NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	ts.Listener.Close()
+	ts.Listener = NewMemListener()
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	res, err := ts.Client().Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(b) != "HTTP/2.0" {
+		t.Errorf("proto = %q; want HTTP/2.0", b)
+	}
+}
+
+func TestMemConnHalfCloseAndDeadlines(t *testing.T) {
+	l := NewMemListener()
+	defer l.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c, err := l.Accept()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer c.Close()
+		b, err := io.ReadAll(c)
+		if err != nil {
+			t.Error(err)
+		}
+		c.Write(b) // echo after the client's half-close
+	}()
+
+	c, err := l.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, err := c.Read(make([]byte, 1)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Read past deadline = %v; want os.ErrDeadlineExceeded", err)
+	}
+	c.SetReadDeadline(time.Time{})
+
+	io.WriteString(c, "hello")
+	if err := c.(interface{ CloseWrite() error }).CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(c)
+	if err != nil || string(b) != "hello" {
+		t.Errorf("read %q, %v; want %q", b, err, "hello")
+	}
+	<-done
+
+	l.Close()
+	if _, err := l.DialContext(context.Background(), "tcp", "ignored"); err == nil {
+		t.Errorf("Dial after Close succeeded; want error")
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Implementation of MockServer
+
+package httptest
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A MockServer is a [Server] that answers requests according to a
+// table of expectations registered by the test, and reports requests
+// that match no expectation, and expectations that were not met, as
+// test failures when it is closed.
+//
+//	ms := httptest.NewMockServer(t)
+//	ms.Expect("GET /users/{id}").WithHeader("Accept", "application/json").
+//		Respond(200, `{"name":"gopher"}`)
+//	ms.Expect("POST /users").Times(2).Respond(201, "")
+//	res, err := ms.Client().Get(ms.URL + "/users/1")
+//
+// Requests are matched against expectations in registration order;
+// the first one that matches and has not used up its calls answers
+// the request. A request matching no expectation is answered with
+// status 501 Not Implemented.
+type MockServer struct {
+	*Server
+
+	tb        testing.TB
+	mu        sync.Mutex // guards exps and unmatched
+	exps      []*Expectation
+	unmatched []string
+	closeOnce sync.Once
+}
+
+// NewMockServer starts and returns a new [MockServer] that reports
+// failures to tb. The server is closed automatically when the test
+// finishes, though the test may call Close earlier to check
+// expectations at a particular point.
+func NewMockServer(tb testing.TB) *MockServer {
+	m := &MockServer{tb: tb}
+	m.Server = NewServer(http.HandlerFunc(m.serveHTTP))
+	tb.Cleanup(m.Close)
+	return m
+}
+
+// Expect registers and returns a new expectation for requests
+// matching pattern, which uses the syntax of [http.ServeMux]
+// patterns, such as "GET /items/{id}" or "example.com/static/".
+// Expect panics if pattern is invalid.
+//
+// By default the expectation must be met at least once and may be
+// met any number of times, and it is answered with an empty 200 OK
+// response.
+func (m *MockServer) Expect(pattern string) *Expectation {
+	e := &Expectation{
+		pattern: pattern,
+		mux:     http.NewServeMux(),
+		min:     1,
+		max:     -1,
+	}
+	e.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		e.matched = r
+	})
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.exps = append(m.exps, e)
+	return e
+}
+
+// Close shuts down the server as for [Server.Close] and reports, as
+// errors on the test, any request that matched no expectation and any
+// expectation that was called fewer times than required. Only the
+// first call to Close checks expectations.
+func (m *MockServer) Close() {
+	m.closeOnce.Do(func() {
+		m.tb.Helper()
+		m.Server.Close()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for _, u := range m.unmatched {
+			m.tb.Errorf("httptest: MockServer received unexpected request %s", u)
+		}
+		for _, e := range m.exps {
+			if e.calls < e.min {
+				m.tb.Errorf("httptest: MockServer expectation %q called %d times; want %s", e.pattern, e.calls, e.wantCalls())
+			}
+		}
+	})
+}
+
+func (m *MockServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	var (
+		e    *Expectation
+		mr   *MockResponse
+		preq *http.Request
+	)
+	for _, x := range m.exps {
+		if preq = x.match(r, body); preq != nil {
+			e = x
+			mr = e.nextResponse()
+			break
+		}
+	}
+	if e == nil {
+		m.unmatched = append(m.unmatched, r.Method+" "+r.URL.RequestURI())
+	}
+	m.mu.Unlock()
+
+	if e == nil {
+		http.Error(w, "httptest: no expectation matches request", http.StatusNotImplemented)
+		return
+	}
+	preq.Body = io.NopCloser(bytes.NewReader(body))
+	mr.serve(w, preq)
+}
+
+// An Expectation describes requests a [MockServer] expects to receive
+// and how to answer them. Its methods configure it and return it, so
+// calls can be chained; they must not be called while the server
+// might be handling a matching request.
+type Expectation struct {
+	pattern   string
+	mux       *http.ServeMux
+	matchers  []func(r *http.Request, body []byte) bool
+	responses []MockResponse
+	min, max  int // max < 0 means no limit
+
+	// Guarded by the MockServer's mu.
+	calls   int
+	matched *http.Request // set by mux's handler during match
+}
+
+// WithHeader restricts the expectation to requests having a header
+// name with the given value among its values.
+func (e *Expectation) WithHeader(name, value string) *Expectation {
+	return e.Match(func(r *http.Request, body []byte) bool {
+		for _, v := range r.Header.Values(name) {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// WithBody restricts the expectation to requests whose body is
+// exactly body.
+func (e *Expectation) WithBody(body string) *Expectation {
+	return e.Match(func(r *http.Request, b []byte) bool {
+		return string(b) == body
+	})
+}
+
+// WithBodyContaining restricts the expectation to requests whose body
+// contains substr.
+func (e *Expectation) WithBodyContaining(substr string) *Expectation {
+	return e.Match(func(r *http.Request, b []byte) bool {
+		return bytes.Contains(b, []byte(substr))
+	})
+}
+
+// Match restricts the expectation to requests for which f reports
+// true. f is given the request, whose path values from the pattern
+// are set, and its body, which has already been read. f is called
+// with the MockServer's lock held and must not block.
+func (e *Expectation) Match(f func(r *http.Request, body []byte) bool) *Expectation {
+	e.matchers = append(e.matchers, f)
+	return e
+}
+
+// Times requires the expectation to be met exactly n times. Once it
+// has been met n times it no longer matches requests, which may then
+// be answered by a later expectation.
+func (e *Expectation) Times(n int) *Expectation {
+	e.min, e.max = n, n
+	return e
+}
+
+// AnyTimes allows the expectation to be met any number of times,
+// including none.
+func (e *Expectation) AnyTimes() *Expectation {
+	e.min, e.max = 0, -1
+	return e
+}
+
+// Respond adds a response with the given status code and body to the
+// expectation's sequence of responses, as for [Expectation.RespondWith].
+func (e *Expectation) Respond(code int, body string) *Expectation {
+	return e.RespondWith(MockResponse{Status: code, Body: body})
+}
+
+// RespondWith adds r to the expectation's sequence of responses. The
+// nth matching request is answered with the nth response; once the
+// sequence is exhausted, its last response is repeated.
+func (e *Expectation) RespondWith(r MockResponse) *Expectation {
+	e.responses = append(e.responses, r)
+	return e
+}
+
+// match reports whether e matches r, returning the request as seen by
+// e's pattern, with its path values set, or nil.
+func (e *Expectation) match(r *http.Request, body []byte) *http.Request {
+	if e.max >= 0 && e.calls >= e.max {
+		return nil
+	}
+	e.matched = nil
+	e.mux.ServeHTTP(discardResponseWriter{}, r)
+	preq := e.matched
+	e.matched = nil
+	if preq == nil {
+		return nil
+	}
+	for _, f := range e.matchers {
+		if !f(preq, body) {
+			return nil
+		}
+	}
+	return preq
+}
+
+// nextResponse records a call of e and returns the response to send.
+func (e *Expectation) nextResponse() *MockResponse {
+	e.calls++
+	if len(e.responses) == 0 {
+		return &MockResponse{}
+	}
+	return &e.responses[min(e.calls, len(e.responses))-1]
+}
+
+func (e *Expectation) wantCalls() string {
+	if e.min == e.max {
+		return fmt.Sprint(e.min)
+	}
+	return fmt.Sprintf("at least %d", e.min)
+}
+
+// A MockResponse is a scripted response of a [MockServer].
+type MockResponse struct {
+	// Status is the response status code. If zero, 200 is used.
+	Status int
+
+	// Header holds headers to send with the response.
+	Header http.Header
+
+	// Body is the response body.
+	Body string
+
+	// Delay is how long to wait before responding. The wait ends
+	// early if the request's context is canceled.
+	Delay time.Duration
+
+	// Handler, if non-nil, answers the request after Delay instead
+	// of Status, Header and Body. The request's path values from the
+	// expectation's pattern are set.
+	Handler http.Handler
+}
+
+func (mr *MockResponse) serve(w http.ResponseWriter, r *http.Request) {
+	if mr.Delay > 0 {
+		t := time.NewTimer(mr.Delay)
+		select {
+		case <-t.C:
+		case <-r.Context().Done():
+			t.Stop()
+			return
+		}
+	}
+	if mr.Handler != nil {
+		mr.Handler.ServeHTTP(w, r)
+		return
+	}
+	for k, vv := range mr.Header {
+		w.Header()[k] = append([]string(nil), vv...)
+	}
+	if mr.Header.Get("Content-Length") == "" && mr.Body != "" {
+		w.Header().Set("Content-Length", strconv.Itoa(len(mr.Body)))
+	}
+	w.WriteHeader(cmp.Or(mr.Status, http.StatusOK))
+	io.WriteString(w, mr.Body)
+}
+
+// discardResponseWriter is the ResponseWriter used when routing a
+// request through an expectation's ServeMux only to match it.
+type discardResponseWriter struct{}
+
+func (discardResponseWriter) Header() http.Header         { return http.Header{} }
+func (discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (discardResponseWriter) WriteHeader(int)             {}
@@ -0,0 +1,102 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httptest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTB records the errors reported by a MockServer.
+type fakeTB struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (tb *fakeTB) Helper() {}
+
+func (tb *fakeTB) Errorf(format string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func (tb *fakeTB) Cleanup(f func()) { tb.cleanups = append(tb.cleanups, f) }
+
+func mockDo(t *testing.T, ms *MockServer, method, path, body string) (int, string) {
+	t.Helper()
+	req, _ := http.NewRequest(method, ms.URL+path, strings.NewReader(body))
+	req.Header.Set("Accept", "application/json")
+	res, err := ms.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode, string(b)
+}
+
+func TestMockServer(t *testing.T) {
+	tb := &fakeTB{TB: t}
+	ms := NewMockServer(tb)
+	ms.Expect("GET /users/{id}").WithHeader("Accept", "application/json").
+		Respond(200, "first").
+		Respond(200, "second")
+	ms.Expect("POST /users").WithBodyContaining("gopher").Times(1).Respond(201, "created")
+	ms.Expect("POST /users").AnyTimes().Respond(409, "conflict")
+	ms.Expect("GET /echo/{name}").RespondWith(MockResponse{
+		Delay: time.Millisecond,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			fmt.Fprintf(w, "%s:%s", r.PathValue("name"), b)
+		}),
+	})
+	ms.Expect("DELETE /users/{id}").Times(2)
+
+	tests := []struct {
+		method, path, body string
+		code               int
+		want               string
+	}{
+		{"GET", "/users/1", "", 200, "first"},
+		{"GET", "/users/2", "", 200, "second"},
+		{"GET", "/users/3", "", 200, "second"},
+		{"POST", "/users", `{"name":"gopher"}`, 201, "created"},
+		{"POST", "/users", `{"name":"gopher"}`, 409, "conflict"},
+		{"GET", "/echo/x", "hi", 200, "x:hi"},
+		{"DELETE", "/users/1", "", 200, ""},
+		{"PUT", "/nowhere", "", 501, ""},
+	}
+	for _, tt := range tests {
+		code, body := mockDo(t, ms, tt.method, tt.path, tt.body)
+		if code != tt.code || (tt.want != "" && body != tt.want) {
+			t.Errorf("%s %s = %d %q; want %d %q", tt.method, tt.path, code, body, tt.code, tt.want)
+		}
+	}
+	if len(tb.errors) != 0 {
+		t.Fatalf("errors reported before Close: %q", tb.errors)
+	}
+
+	for _, f := range tb.cleanups {
+		f()
+	}
+	want := []string{
+		"httptest: MockServer received unexpected request PUT /nowhere",
+		`httptest: MockServer expectation "DELETE /users/{id}" called 1 times; want 2`,
+	}
+	if strings.Join(tb.errors, "\n") != strings.Join(want, "\n") {
+		t.Errorf("reported errors:\n%s\nwant:\n%s", strings.Join(tb.errors, "\n"), strings.Join(want, "\n"))
+	}
+	ms.Close() // idempotent
+	if len(tb.errors) != len(want) {
+		t.Errorf("second Close reported more errors: %q", tb.errors)
+	}
+}
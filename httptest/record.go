@@ -0,0 +1,173 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Request recording for Server
+
+package httptest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// A RecordedRequest is a copy of a request received by a [Server]
+// with RecordRequests set.
+type RecordedRequest struct {
+	Method     string
+	URL        *url.URL
+	Proto      string // "HTTP/1.1", "HTTP/2.0", ...
+	Host       string
+	Header     http.Header
+	Body       []byte
+	Trailer    http.Header
+	RemoteAddr string
+}
+
+// requestLog holds the requests recorded by a Server.
+type requestLog struct {
+	mu      sync.Mutex
+	reqs    []RecordedRequest
+	changed chan struct{} // closed and replaced when reqs changes
+}
+
+// notifyLocked wakes any goroutines waiting for reqs to change.
+// l.mu must be held.
+func (l *requestLog) notifyLocked() {
+	if l.changed != nil {
+		close(l.changed)
+		l.changed = nil
+	}
+}
+
+// recordHandler returns a handler that passes requests to h and
+// records them in s.rec.
+func (s *Server) recordHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body bytes.Buffer
+		rr := RecordedRequest{
+			Method:     r.Method,
+			URL:        cloneURL(r.URL),
+			Proto:      r.Proto,
+			Host:       r.Host,
+			Header:     r.Header.Clone(),
+			RemoteAddr: r.RemoteAddr,
+		}
+		if r.Body != nil {
+			r.Body = teeReadCloser{io.TeeReader(r.Body, &body), r.Body}
+		}
+		defer func() {
+			// Read whatever the handler left unread so the
+			// recorded body and trailer are complete, unless the
+			// handler took over the connection.
+			if r.Body != nil && s.connTracked(r.Context()) {
+				io.Copy(io.Discard, r.Body)
+			}
+			rr.Body = body.Bytes()
+			rr.Trailer = r.Trailer.Clone()
+			s.rec.mu.Lock()
+			s.rec.reqs = append(s.rec.reqs, rr)
+			s.rec.notifyLocked()
+			s.rec.mu.Unlock()
+		}()
+		h.ServeHTTP(w, r)
+	})
+}
+
+// connTracked reports whether the connection carrying the request with
+// context ctx is still tracked, that is, neither hijacked nor closed.
+func (s *Server) connTracked(ctx context.Context) bool {
+	c := connFromContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.conns[c]
+	return ok
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func cloneURL(u *url.URL) *url.URL {
+	if u == nil {
+		return nil
+	}
+	u2 := *u
+	if u.User != nil {
+		u2.User = new(url.Userinfo)
+		*u2.User = *u.User
+	}
+	return &u2
+}
+
+// Requests returns the requests received by the server since it
+// started or since the last call to ResetRequests, in the order their
+// handlers finished. A request is recorded once its handler returns,
+// with any body the handler did not read drained so that Body and
+// Trailer are complete.
+//
+// Requests returns nil unless RecordRequests was set before the
+// server was started.
+func (s *Server) Requests() []RecordedRequest {
+	s.rec.mu.Lock()
+	defer s.rec.mu.Unlock()
+	return append([]RecordedRequest(nil), s.rec.reqs...)
+}
+
+// RequestsForPath returns the recorded requests whose URL path is
+// path, in the order they were recorded.
+func (s *Server) RequestsForPath(path string) []RecordedRequest {
+	s.rec.mu.Lock()
+	defer s.rec.mu.Unlock()
+	var reqs []RecordedRequest
+	for _, rr := range s.rec.reqs {
+		if rr.URL.Path == path {
+			reqs = append(reqs, rr)
+		}
+	}
+	return reqs
+}
+
+// WaitForRequests waits until at least n requests have been recorded
+// and returns them, as for Requests. If fewer than n requests are
+// recorded within timeout, it returns those that were together with
+// an error.
+func (s *Server) WaitForRequests(n int, timeout time.Duration) ([]RecordedRequest, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		s.rec.mu.Lock()
+		if len(s.rec.reqs) >= n {
+			reqs := append([]RecordedRequest(nil), s.rec.reqs...)
+			s.rec.mu.Unlock()
+			return reqs, nil
+		}
+		if s.rec.changed == nil {
+			s.rec.changed = make(chan struct{})
+		}
+		changed := s.rec.changed
+		s.rec.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			reqs := s.Requests()
+			return reqs, fmt.Errorf("httptest: got %d requests after %v; want %d", len(reqs), timeout, n)
+		}
+	}
+}
+
+// ResetRequests discards the recorded requests.
+func (s *Server) ResetRequests() {
+	s.rec.mu.Lock()
+	defer s.rec.mu.Unlock()
+	s.rec.reqs = nil
+	s.rec.notifyLocked()
+}
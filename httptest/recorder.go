@@ -42,11 +42,32 @@ type ResponseRecorder struct {
 	// Flushed is whether the Handler called Flush.
 	Flushed bool
 
+	// Interim holds the informational (1xx) responses written by the
+	// Handler before the final response, such as 100 Continue or
+	// 103 Early Hints, in the order they were written. As with a real
+	// server, WriteHeader with a 1xx code other than 101 Switching
+	// Protocols sends an interim response with the headers set so far,
+	// and does not prevent a later call from setting the final code.
+	Interim []InterimResponse
+
 	result      *http.Response // cache of Result's return value
 	snapHeader  http.Header    // snapshot of HeaderMap at first Write
 	wroteHeader bool
 }
 
+// An InterimResponse is an informational (1xx) response recorded by a
+// [ResponseRecorder] or [StreamRecorder].
+type InterimResponse struct {
+	Code   int
+	Header http.Header // snapshot of the headers when it was written
+}
+
+// isInterim reports whether code is the status code of an interim
+// response, which the handler may follow with another response.
+func isInterim(code int) bool {
+	return code >= 100 && code <= 199 && code != http.StatusSwitchingProtocols
+}
+
 // NewRecorder returns an initialized [ResponseRecorder].
 func NewRecorder() *ResponseRecorder {
 	return &ResponseRecorder{
@@ -146,6 +167,10 @@ func (rw *ResponseRecorder) WriteHeader(code int) {
 	}
 
 	checkWriteHeaderCode(code)
+	if isInterim(code) {
+		rw.Interim = append(rw.Interim, InterimResponse{Code: code, Header: rw.Header().Clone()})
+		return
+	}
 	rw.Code = code
 	rw.wroteHeader = true
 	if rw.HeaderMap == nil {
@@ -154,6 +179,13 @@ func (rw *ResponseRecorder) WriteHeader(code int) {
 	rw.snapHeader = rw.HeaderMap.Clone()
 }
 
+// WritesInterimResponses reports true: WriteHeader records 1xx codes
+// other than 101 in rw.Interim rather than taking them as the final
+// status.
+func (rw *ResponseRecorder) WritesInterimResponses() bool {
+	return true
+}
+
 // Flush implements [http.Flusher]. To test whether Flush was
 // called, see rw.Flushed.
 func (rw *ResponseRecorder) Flush() {
@@ -204,8 +236,18 @@ func (rw *ResponseRecorder) Result() *http.Response {
 	}
 	res.ContentLength = parseContentLength(res.Header.Get("Content-Length"))
 
-	if trailers, ok := rw.snapHeader["Trailer"]; ok {
-		res.Trailer = make(http.Header, len(trailers))
+	res.Trailer = trailers(rw.snapHeader, rw.HeaderMap)
+	return res
+}
+
+// trailers returns the trailers of a response whose headers were snap
+// when written and are h after the handler finished: the keys declared
+// in snap's Trailer header, and those set in h with
+// [http.TrailerPrefix]. It returns nil if there are none.
+func trailers(snap, h http.Header) http.Header {
+	var res http.Header
+	if trailers, ok := snap["Trailer"]; ok {
+		res = make(http.Header, len(trailers))
 		for _, k := range trailers {
 			for _, k := range strings.Split(k, ",") {
 				k = http.CanonicalHeaderKey(textproto.TrimString(k))
@@ -213,25 +255,25 @@ func (rw *ResponseRecorder) Result() *http.Response {
 					// Ignore since forbidden by RFC 7230, section 4.1.2.
 					continue
 				}
-				vv, ok := rw.HeaderMap[k]
+				vv, ok := h[k]
 				if !ok {
 					continue
 				}
 				vv2 := make([]string, len(vv))
 				copy(vv2, vv)
-				res.Trailer[k] = vv2
+				res[k] = vv2
 			}
 		}
 	}
-	for k, vv := range rw.HeaderMap {
+	for k, vv := range h {
 		if !strings.HasPrefix(k, http.TrailerPrefix) {
 			continue
 		}
-		if res.Trailer == nil {
-			res.Trailer = make(http.Header)
+		if res == nil {
+			res = make(http.Header)
 		}
 		for _, v := range vv {
-			res.Trailer.Add(strings.TrimPrefix(k, http.TrailerPrefix), v)
+			res.Add(strings.TrimPrefix(k, http.TrailerPrefix), v)
 		}
 	}
 	return res
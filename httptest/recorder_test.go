@@ -369,3 +369,38 @@ func TestRecorderPanicsOnNonXXXStatusCode(t *testing.T) {
 		})
 	}
 }
+
+func TestRecorderInterim(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.Header().Add("Link", "</script.js>; rel=preload; as=script")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusOK)
+		w.WriteHeader(http.StatusContinue) // superfluous after the final response
+	}
+	rec := NewRecorder()
+	handler(rec, NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Code = %d; want 200", rec.Code)
+	}
+	if len(rec.Interim) != 2 {
+		t.Fatalf("got %d interim responses; want 2", len(rec.Interim))
+	}
+	for i, want := range []int{1, 2} {
+		ir := rec.Interim[i]
+		if ir.Code != http.StatusEarlyHints || len(ir.Header.Values("Link")) != want {
+			t.Errorf("Interim[%d] = %d with Link %q; want 103 with %d links", i, ir.Code, ir.Header.Values("Link"), want)
+		}
+	}
+	if got := len(rec.Result().Header.Values("Link")); got != 2 {
+		t.Errorf("final response has %d Link headers; want 2", got)
+	}
+
+	rec = NewRecorder()
+	rec.WriteHeader(http.StatusSwitchingProtocols)
+	if rec.Code != http.StatusSwitchingProtocols || len(rec.Interim) != 0 {
+		t.Errorf("after 101, Code = %d, %d interim responses; want 101, 0", rec.Code, len(rec.Interim))
+	}
+}
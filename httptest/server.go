@@ -7,6 +7,7 @@
 package httptest
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"flag"
@@ -33,6 +34,30 @@ type Server struct {
 	// NewUnstartedServer and calling Server.StartTLS.
 	EnableHTTP2 bool
 
+	// RecordRequests controls whether the server records a copy of
+	// every request it receives, for inspection with Requests and
+	// related methods. It must be set between calling
+	// NewUnstartedServer and calling Start or StartTLS.
+	RecordRequests bool
+
+	// Faults, if non-nil, enables fault injection, and lists the
+	// faults to inject; the first one matching a request applies.
+	// It must be set between calling NewUnstartedServer and calling
+	// Start or StartTLS. Use SetFaults to change the faults later.
+	Faults []Fault
+
+	// CA, if set before StartTLS, is the certificate authority that
+	// issues the server's certificate, unless TLS.Certificates is
+	// set, and that the Server's clients trust. It is set
+	// automatically by StartTLS when RequireClientCert is set.
+	CA *CA
+
+	// RequireClientCert controls whether the server requires and
+	// verifies a client certificate issued by CA, or by the
+	// TLS.ClientCAs if set. The Server's Client then presents such a
+	// certificate. It must be set before calling StartTLS.
+	RequireClientCert bool
+
 	// TLS is the optional TLS configuration, populated with a new config
 	// after TLS is started. If set on an unstarted server before StartTLS
 	// is called, existing fields are copied into the new config.
@@ -42,16 +67,29 @@ type Server struct {
 	// before Start or StartTLS.
 	Config *http.Server
 
+	certMu sync.Mutex // guards certificate and rotatedTLS
 	// certificate is a parsed version of the TLS config certificate, if present.
 	certificate *x509.Certificate
+	rotatedTLS  *tls.Config // set by SetCertificate
+	rotatable   bool        // TLS.GetConfigForClient is getConfigForClient
+
+	rootCAs     *x509.CertPool // trusted by clients of a TLS server
+	memListener *MemListener   // Listener, if it is a MemListener
 
 	// wg counts the number of outstanding HTTP requests on this server.
 	// Close blocks until all requests are finished.
 	wg sync.WaitGroup
 
-	mu     sync.Mutex // guards closed and conns
-	closed bool
-	conns  map[net.Conn]http.ConnState // except terminal states
+	mu              sync.Mutex // guards closed, conns and extraTransports
+	closed          bool
+	conns           map[net.Conn]http.ConnState // except terminal states
+	extraTransports []*http.Transport           // of clients from ClientWithCert
+
+	rec requestLog // requests recorded when RecordRequests is set
+
+	faultMu  sync.Mutex // guards faultsOn and faults
+	faultsOn bool
+	faults   []Fault
 
 	// client is configured for use with the server.
 	// Its transport is automatically closed when Close is called.
@@ -130,6 +168,11 @@ func (s *Server) Start() {
 	if s.client == nil {
 		s.client = &http.Client{Transport: &http.Transport{}}
 	}
+	s.memListener, _ = s.Listener.(*MemListener)
+	if t, ok := s.client.Transport.(*http.Transport); ok {
+		s.dialMem(t)
+	}
+	s.wrapFaults()
 	s.URL = "http://" + s.Listener.Addr().String()
 	s.wrap()
 	s.goServe()
@@ -165,21 +208,41 @@ func (s *Server) StartTLS() {
 		}
 		s.TLS.NextProtos = nextProtos
 	}
+	if s.RequireClientCert && s.CA == nil {
+		s.CA = NewCA()
+	}
 	if len(s.TLS.Certificates) == 0 {
+		if s.CA != nil {
+			cert = s.CA.IssueServerCert(serverHosts...)
+		}
 		s.TLS.Certificates = []tls.Certificate{cert}
 	}
+	if s.RequireClientCert {
+		s.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+		if s.TLS.ClientCAs == nil {
+			s.TLS.ClientCAs = s.CA.Pool()
+		}
+	}
+	if s.TLS.GetConfigForClient == nil {
+		s.TLS.GetConfigForClient = s.getConfigForClient
+		s.rotatable = true
+	}
 	s.certificate, err = x509.ParseCertificate(s.TLS.Certificates[0].Certificate[0])
 	if err != nil {
 		panic(fmt.Sprintf("httptest: NewTLSServer: %v", err))
 	}
-	certpool := x509.NewCertPool()
-	certpool.AddCert(s.certificate)
-	s.client.Transport = &http.Transport{
-		TLSClientConfig: &tls.Config{
-			RootCAs: certpool,
-		},
-		ForceAttemptHTTP2: s.EnableHTTP2,
+	s.rootCAs = x509.NewCertPool()
+	s.rootCAs.AddCert(s.certificate)
+	if s.CA != nil {
+		s.rootCAs.AddCert(s.CA.Certificate)
 	}
+	s.memListener, _ = s.Listener.(*MemListener)
+	var clientCerts []tls.Certificate
+	if s.RequireClientCert {
+		clientCerts = append(clientCerts, s.CA.IssueClientCert("client"))
+	}
+	s.client.Transport = s.newTLSTransport(clientCerts...)
+	s.wrapFaults()
 	s.Listener = tls.NewListener(s.Listener, s.TLS)
 	s.URL = "https://" + s.Listener.Addr().String()
 	s.wrap()
@@ -248,6 +311,12 @@ func (s *Server) Close() {
 			t.CloseIdleConnections()
 		}
 	}
+	s.mu.Lock()
+	extraTransports := s.extraTransports
+	s.mu.Unlock()
+	for _, t := range extraTransports {
+		t.CloseIdleConnections()
+	}
 
 	s.wg.Wait()
 }
@@ -294,11 +363,14 @@ func (s *Server) CloseClientConnections() {
 // Certificate returns the certificate used by the server, or nil if
 // the server doesn't use TLS.
 func (s *Server) Certificate() *x509.Certificate {
+	s.certMu.Lock()
+	defer s.certMu.Unlock()
 	return s.certificate
 }
 
 // Client returns an HTTP client configured for making requests to the server.
-// It is configured to trust the server's TLS test certificate and will
+// It is configured to trust the server's TLS test certificate, or its CA,
+// to present a client certificate if the server requires one, and will
 // close its idle connections on [Server.Close].
 // Use Server.URL as the base URL to send requests to the server.
 func (s *Server) Client() *http.Client {
@@ -314,8 +386,22 @@ func (s *Server) goServe() {
 }
 
 // wrap installs the connection state-tracking hook to know which
-// connections are idle.
+// connections are idle, and the request recorder if enabled.
 func (s *Server) wrap() {
+	if s.RecordRequests || s.faultsOn {
+		h := s.Config.Handler
+		if h == nil {
+			h = http.DefaultServeMux
+		}
+		if s.RecordRequests {
+			h = s.recordHandler(h)
+		}
+		if s.faultsOn {
+			h = s.faultHandler(h)
+		}
+		s.Config.Handler = h
+		s.trackConnContext()
+	}
 	oldHook := s.Config.ConnState
 	s.Config.ConnState = func(c net.Conn, cs http.ConnState) {
 		s.mu.Lock()
@@ -373,6 +459,26 @@ func (s *Server) wrap() {
 	}
 }
 
+type connContextKey struct{}
+
+// trackConnContext installs a ConnContext hook recording in each
+// request's context the connection it arrived on.
+func (s *Server) trackConnContext() {
+	oldConnContext := s.Config.ConnContext
+	s.Config.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+		if oldConnContext != nil {
+			ctx = oldConnContext(ctx, c)
+		}
+		return context.WithValue(ctx, connContextKey{}, c)
+	}
+}
+
+// connFromContext returns the connection recorded by trackConnContext.
+func connFromContext(ctx context.Context) net.Conn {
+	c, _ := ctx.Value(connContextKey{}).(net.Conn)
+	return c
+}
+
 // closeConn closes c.
 // s.mu must be held.
 func (s *Server) closeConn(c net.Conn) { s.closeConnChan(c, nil) }
@@ -9,8 +9,10 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 type newServerFunc func(http.Handler) *Server
@@ -293,3 +295,92 @@ func TestTLSServerWithHTTP2(t *testing.T) {
 		})
 	}
 }
+
+func TestServerRecordRequests(t *testing.T) {
+	ts := NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Leave the body unread; the recorder drains it.
+	}))
+	ts.RecordRequests = true
+	ts.Start()
+	defer ts.Close()
+
+	const n = 5
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			path := "/a"
+			if i == 0 {
+				path = "/b"
+			}
+			req, _ := http.NewRequest("POST", ts.URL+path+"?q=1", strings.NewReader("body"))
+			req.Header.Set("X-Test", "yes")
+			res, err := ts.Client().Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			res.Body.Close()
+		}()
+	}
+	reqs, err := ts.WaitForRequests(n, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	for _, rr := range reqs {
+		if rr.Method != "POST" || rr.URL.RawQuery != "q=1" || rr.Proto != "HTTP/1.1" ||
+			rr.Header.Get("X-Test") != "yes" || string(rr.Body) != "body" || rr.RemoteAddr == "" {
+			t.Errorf("recorded request = %+v", rr)
+		}
+	}
+	if got := len(ts.RequestsForPath("/b")); got != 1 {
+		t.Errorf("RequestsForPath(/b) returned %d requests; want 1", got)
+	}
+
+	ts.ResetRequests()
+	if got := len(ts.Requests()); got != 0 {
+		t.Errorf("after ResetRequests, %d requests; want 0", got)
+	}
+	if _, err := ts.WaitForRequests(1, 10*time.Millisecond); err == nil {
+		t.Errorf("WaitForRequests with no requests succeeded; want timeout")
+	}
+}
+
+func TestServerRecordTrailer(t *testing.T) {
+	ts := NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.RecordRequests = true
+	ts.Start()
+	defer ts.Close()
+
+	req, _ := http.NewRequest("PUT", ts.URL, nil)
+	req.Trailer = http.Header{"X-Sum": nil}
+	req.Body = io.NopCloser(&trailerSetter{r: strings.NewReader("chunked"), req: req})
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	reqs := ts.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("recorded %d requests; want 1", len(reqs))
+	}
+	if got := reqs[0].Trailer.Get("X-Sum"); got != "42" || string(reqs[0].Body) != "chunked" {
+		t.Errorf("recorded body %q, trailer X-Sum %q; want %q, %q", reqs[0].Body, got, "chunked", "42")
+	}
+}
+
+// trailerSetter sets the X-Sum trailer of req once r is exhausted.
+type trailerSetter struct {
+	r   io.Reader
+	req *http.Request
+}
+
+func (ts *trailerSetter) Read(p []byte) (int, error) {
+	n, err := ts.r.Read(p)
+	if err == io.EOF {
+		ts.req.Trailer.Set("X-Sum", "42")
+	}
+	return n, err
+}
@@ -0,0 +1,368 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httptest
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// A StreamWrite is one event in the output of a handler recorded by
+// a [StreamRecorder]: either a write of Data, or a call to Flush.
+type StreamWrite struct {
+	Data  []byte
+	Flush bool
+}
+
+// StreamRecorder is an implementation of [http.ResponseWriter] for
+// testing handlers that stream their responses, such as server-sent
+// events or long-polling handlers.
+//
+// Unlike [ResponseRecorder], a StreamRecorder may be read while the
+// handler is running. As with a real server, the response header and
+// body become visible to the test only when the handler flushes them
+// or returns:
+//
+//	rec := httptest.NewStreamRecorder()
+//	go rec.Serve(handler, req)
+//	res := rec.Result() // waits for the header
+//	br := bufio.NewReader(res.Body)
+//	line, err := br.ReadString('\n') // waits for a flush
+//
+// It also records the sequence of writes and flushes, supports
+// Hijack with an in-memory connection, and implements the methods
+// used by [http.ResponseController].
+//
+// Writes never block, so a handler completes even if the test does not
+// read the body.
+type StreamRecorder struct {
+	mu          sync.Mutex
+	changed     chan struct{} // closed and replaced when state changes
+	header      http.Header
+	snapHeader  http.Header // header at WriteHeader
+	code        int
+	wroteHeader bool
+	sentHeader  bool   // header flushed
+	body        []byte // everything written
+	flushed     int    // length of body flushed
+	writes      []StreamWrite
+	interim     []InterimResponse
+	done        bool
+	conn        net.Conn // client end of the hijacked connection
+	result      *http.Response
+
+	readDeadline  time.Time
+	writeDeadline time.Time
+	fullDuplex    bool
+}
+
+// NewStreamRecorder returns an initialized [StreamRecorder].
+func NewStreamRecorder() *StreamRecorder {
+	return &StreamRecorder{header: make(http.Header)}
+}
+
+// notifyLocked wakes goroutines waiting for rw to change.
+// rw.mu must be held.
+func (rw *StreamRecorder) notifyLocked() {
+	if rw.changed != nil {
+		close(rw.changed)
+		rw.changed = nil
+	}
+}
+
+// waitLocked waits until rw changes.
+// rw.mu must be held; it is released while waiting.
+func (rw *StreamRecorder) waitLocked() {
+	if rw.changed == nil {
+		rw.changed = make(chan struct{})
+	}
+	changed := rw.changed
+	rw.mu.Unlock()
+	<-changed
+	rw.mu.Lock()
+}
+
+// Serve calls h.ServeHTTP(rw, r) and then Finish.
+func (rw *StreamRecorder) Serve(h http.Handler, r *http.Request) {
+	defer rw.Finish()
+	h.ServeHTTP(rw, r)
+}
+
+// Finish marks the handler as finished, making everything it wrote
+// visible. It must be called when the handler returns, unless the
+// handler is run with Serve.
+func (rw *StreamRecorder) Finish() {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if rw.conn == nil && !rw.wroteHeader {
+		rw.writeHeaderLocked(http.StatusOK)
+	}
+	rw.done = true
+	rw.sentHeader = true
+	rw.flushed = len(rw.body)
+	rw.notifyLocked()
+}
+
+// Header implements [http.ResponseWriter].
+func (rw *StreamRecorder) Header() http.Header {
+	return rw.header
+}
+
+// WriteHeader implements [http.ResponseWriter].
+func (rw *StreamRecorder) WriteHeader(code int) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if rw.wroteHeader || rw.conn != nil {
+		return
+	}
+	checkWriteHeaderCode(code)
+	if isInterim(code) {
+		rw.interim = append(rw.interim, InterimResponse{Code: code, Header: rw.header.Clone()})
+		rw.notifyLocked()
+		return
+	}
+	rw.writeHeaderLocked(code)
+}
+
+func (rw *StreamRecorder) writeHeaderLocked(code int) {
+	rw.code = code
+	rw.wroteHeader = true
+	rw.snapHeader = rw.header.Clone()
+}
+
+// Write implements [http.ResponseWriter]. It returns
+// [os.ErrDeadlineExceeded] if the write deadline has passed and
+// [http.ErrHijacked] after Hijack.
+func (rw *StreamRecorder) Write(b []byte) (int, error) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if err := rw.writeErrLocked(); err != nil {
+		return 0, err
+	}
+	if !rw.wroteHeader {
+		if _, hasType := rw.header["Content-Type"]; !hasType && rw.header.Get("Transfer-Encoding") == "" {
+			rw.header.Set("Content-Type", http.DetectContentType(b))
+		}
+		rw.writeHeaderLocked(http.StatusOK)
+	}
+	rw.body = append(rw.body, b...)
+	rw.writes = append(rw.writes, StreamWrite{Data: bytes.Clone(b)})
+	return len(b), nil
+}
+
+// WriteString implements [io.StringWriter].
+func (rw *StreamRecorder) WriteString(s string) (int, error) {
+	return rw.Write([]byte(s))
+}
+
+func (rw *StreamRecorder) writeErrLocked() error {
+	switch {
+	case rw.conn != nil:
+		return http.ErrHijacked
+	case rw.done:
+		return errors.New("httptest: write after handler finished")
+	case !rw.writeDeadline.IsZero() && !time.Now().Before(rw.writeDeadline):
+		return os.ErrDeadlineExceeded
+	}
+	return nil
+}
+
+// Flush implements [http.Flusher].
+func (rw *StreamRecorder) Flush() {
+	rw.FlushError()
+}
+
+// FlushError flushes the header and any written data, making them
+// visible to readers of the response. It is used by
+// [http.ResponseController.Flush].
+func (rw *StreamRecorder) FlushError() error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if err := rw.writeErrLocked(); err != nil {
+		return err
+	}
+	if !rw.wroteHeader {
+		rw.writeHeaderLocked(http.StatusOK)
+	}
+	rw.sentHeader = true
+	rw.flushed = len(rw.body)
+	rw.writes = append(rw.writes, StreamWrite{Flush: true})
+	rw.notifyLocked()
+	return nil
+}
+
+// Hijack implements [http.Hijacker]. The returned connection is one
+// end of an in-memory connection; the other end is returned by
+// [StreamRecorder.Conn]. Hijack fails if the header has been flushed.
+func (rw *StreamRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	switch {
+	case rw.conn != nil:
+		return nil, nil, http.ErrHijacked
+	case rw.sentHeader || rw.done:
+		return nil, nil, errors.New("httptest: Hijack after response was sent")
+	}
+	client, server := newMemConnPair(memAddr(DefaultRemoteAddr+":1234"), memAddr("192.0.2.1:80"))
+	rw.conn = client
+	rw.notifyLocked()
+	return server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nil
+}
+
+// Conn returns the client end of the connection hijacked by the
+// handler, or nil if it has not called Hijack.
+func (rw *StreamRecorder) Conn() net.Conn {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	return rw.conn
+}
+
+// SetReadDeadline records the read deadline set by the handler with
+// [http.ResponseController.SetReadDeadline]. The recorder does not
+// control the request body, so the deadline has no other effect.
+func (rw *StreamRecorder) SetReadDeadline(t time.Time) error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	rw.readDeadline = t
+	return nil
+}
+
+// SetWriteDeadline sets the deadline after which writes and flushes
+// fail, as used by [http.ResponseController.SetWriteDeadline].
+func (rw *StreamRecorder) SetWriteDeadline(t time.Time) error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	rw.writeDeadline = t
+	return nil
+}
+
+// EnableFullDuplex records that the handler enabled full-duplex mode
+// with [http.ResponseController.EnableFullDuplex].
+func (rw *StreamRecorder) EnableFullDuplex() error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	rw.fullDuplex = true
+	return nil
+}
+
+// Deadlines returns the read and write deadlines last set by the
+// handler.
+func (rw *StreamRecorder) Deadlines() (read, write time.Time) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	return rw.readDeadline, rw.writeDeadline
+}
+
+// FullDuplex reports whether the handler enabled full-duplex mode.
+func (rw *StreamRecorder) FullDuplex() bool {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	return rw.fullDuplex
+}
+
+// Interim returns the informational (1xx) responses written by the
+// handler so far, as for [ResponseRecorder.Interim]. Unlike the final
+// header, they are visible as soon as they are written.
+func (rw *StreamRecorder) Interim() []InterimResponse {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	return append([]InterimResponse(nil), rw.interim...)
+}
+
+// WritesInterimResponses reports true: WriteHeader records 1xx codes
+// other than 101, as returned by Interim, rather than taking them as
+// the final status.
+func (rw *StreamRecorder) WritesInterimResponses() bool {
+	return true
+}
+
+// Writes returns the sequence of writes and flushes made by the
+// handler so far.
+func (rw *StreamRecorder) Writes() []StreamWrite {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	return append([]StreamWrite(nil), rw.writes...)
+}
+
+// Result waits until the handler flushes the response header, returns,
+// or hijacks the connection, and returns the response. It returns nil
+// if the connection was hijacked. Reads from the response's Body
+// block until more of the body is flushed, and return [io.EOF] once
+// the handler has finished and the body has been read; the Trailer
+// field is then populated.
+//
+// Result returns the same response each time it is called.
+func (rw *StreamRecorder) Result() *http.Response {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	for !rw.sentHeader && rw.conn == nil {
+		rw.waitLocked()
+	}
+	if rw.conn != nil && !rw.wroteHeader {
+		return nil
+	}
+	if rw.result != nil {
+		return rw.result
+	}
+	res := &http.Response{
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		StatusCode:    rw.code,
+		Status:        fmt.Sprintf("%03d %s", rw.code, http.StatusText(rw.code)),
+		Header:        rw.snapHeader,
+		ContentLength: parseContentLength(rw.snapHeader.Get("Content-Length")),
+	}
+	res.Body = &streamBody{rw: rw, res: res}
+	rw.result = res
+	return res
+}
+
+// A streamBody is the Body of a response returned by
+// StreamRecorder.Result.
+type streamBody struct {
+	rw     *StreamRecorder
+	res    *http.Response
+	off    int
+	closed bool
+}
+
+func (b *streamBody) Read(p []byte) (int, error) {
+	rw := b.rw
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	for {
+		switch {
+		case b.closed:
+			return 0, errors.New("httptest: read on closed response body")
+		case b.off < rw.flushed:
+			n := copy(p, rw.body[b.off:rw.flushed])
+			b.off += n
+			return n, nil
+		case rw.done || rw.conn != nil:
+			if b.res.Trailer == nil {
+				b.res.Trailer = trailers(rw.snapHeader, rw.header)
+			}
+			return 0, io.EOF
+		}
+		rw.waitLocked()
+	}
+}
+
+func (b *streamBody) Close() error {
+	b.rw.mu.Lock()
+	defer b.rw.mu.Unlock()
+	b.closed = true
+	b.rw.notifyLocked()
+	return nil
+}
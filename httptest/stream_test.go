@@ -0,0 +1,140 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httptest
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStreamRecorder(t *testing.T) {
+	next := make(chan bool)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Trailer", "X-Count")
+		for i := 0; <-next; i++ {
+			io.WriteString(w, "data: ")
+			io.WriteString(w, "event\n")
+			w.(http.Flusher).Flush()
+		}
+		w.Header().Set("X-Count", "2")
+	})
+	rec := NewStreamRecorder()
+	go rec.Serve(h, NewRequest("GET", "/events", nil))
+
+	next <- true
+	res := rec.Result()
+	if res.StatusCode != 200 || res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("response = %d %v", res.StatusCode, res.Header)
+	}
+	br := bufio.NewReader(res.Body)
+	for i := 0; i < 2; i++ {
+		if i > 0 {
+			next <- true
+		}
+		line, err := br.ReadString('\n')
+		if err != nil || line != "data: event\n" {
+			t.Fatalf("event %d = %q, %v", i, line, err)
+		}
+	}
+	next <- false
+	if b, err := io.ReadAll(br); err != nil || len(b) != 0 {
+		t.Errorf("rest of body = %q, %v; want EOF", b, err)
+	}
+	if got := res.Trailer.Get("X-Count"); got != "2" {
+		t.Errorf("trailer X-Count = %q; want 2", got)
+	}
+
+	want := []StreamWrite{
+		{Data: []byte("data: ")}, {Data: []byte("event\n")}, {Flush: true},
+		{Data: []byte("data: ")}, {Data: []byte("event\n")}, {Flush: true},
+	}
+	if got := rec.Writes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Writes = %v; want %v", got, want)
+	}
+}
+
+func TestStreamRecorderHijack(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer c.Close()
+		line, _ := brw.ReadString('\n')
+		brw.WriteString("echo: " + line)
+		brw.Flush()
+	})
+	rec := NewStreamRecorder()
+	go rec.Serve(h, NewRequest("GET", "/", nil))
+	if res := rec.Result(); res != nil {
+		t.Fatalf("Result after Hijack = %v; want nil", res)
+	}
+	c := rec.Conn()
+	io.WriteString(c, "ping\n")
+	b, err := io.ReadAll(c)
+	if err != nil || string(b) != "echo: ping\n" {
+		t.Errorf("read %q, %v; want %q", b, err, "echo: ping\n")
+	}
+}
+
+func TestStreamRecorderResponseController(t *testing.T) {
+	deadline := time.Now().Add(-time.Second)
+	done := make(chan error, 1)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		if err := rc.EnableFullDuplex(); err != nil {
+			t.Error(err)
+		}
+		if err := rc.SetReadDeadline(deadline); err != nil {
+			t.Error(err)
+		}
+		if err := rc.SetWriteDeadline(deadline); err != nil {
+			t.Error(err)
+		}
+		_, err := w.Write([]byte("late"))
+		done <- err
+	})
+	rec := NewStreamRecorder()
+	rec.Serve(h, NewRequest("GET", "/", nil))
+	if err := <-done; !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Write after write deadline = %v; want os.ErrDeadlineExceeded", err)
+	}
+	if rd, wd := rec.Deadlines(); !rd.Equal(deadline) || !wd.Equal(deadline) {
+		t.Errorf("Deadlines = %v, %v; want %v", rd, wd, deadline)
+	}
+	if !rec.FullDuplex() {
+		t.Errorf("FullDuplex = false; want true")
+	}
+}
+
+func TestStreamRecorderInterim(t *testing.T) {
+	proceed := make(chan bool)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload")
+		w.WriteHeader(http.StatusEarlyHints)
+		<-proceed
+		w.WriteHeader(http.StatusNoContent)
+	})
+	rec := NewStreamRecorder()
+	go rec.Serve(h, NewRequest("GET", "/", nil))
+	for len(rec.Interim()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if ir := rec.Interim()[0]; ir.Code != http.StatusEarlyHints || ir.Header.Get("Link") == "" {
+		t.Errorf("interim response = %d %v", ir.Code, ir.Header)
+	}
+	close(proceed)
+	if res := rec.Result(); res.StatusCode != http.StatusNoContent {
+		t.Errorf("final status = %d; want 204", res.StatusCode)
+	}
+}
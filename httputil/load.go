@@ -0,0 +1,228 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httputil
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// headerOnly reports whether dump ends immediately after its header
+// block, as produced by the dump functions when body is false.
+func headerOnly(dump []byte) bool {
+	i := bytes.Index(dump, []byte("\r\n\r\n"))
+	return i >= 0 && i+4 == len(dump)
+}
+
+// readBody reads all of body into memory, which also populates any
+// trailers, and returns the bytes read.
+func readBody(body io.ReadCloser) ([]byte, error) {
+	if body == nil || body == http.NoBody {
+		return nil, nil
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// LoadRequest parses dump, the HTTP/1.x wire representation of a
+// request as produced by [DumpRequest] or [DumpRequestOut], and
+// returns the request it describes.
+//
+// Chunked bodies are decoded and any trailers are stored in the
+// request's Trailer field. The body is read entirely into memory, and
+// the returned request's GetBody returns a fresh copy of it, so the
+// request may be sent more than once. A dump that ends after its
+// header, as written when the body is not dumped, yields an empty body
+// while ContentLength keeps the declared value.
+//
+// The returned request is suitable for sending with an [http.Client]:
+// its RequestURI is cleared and, unless the dump used an absolute
+// request target, its URL is made absolute using the Host header and
+// the "http" scheme.
+func LoadRequest(dump []byte) (*http.Request, error) {
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(dump)))
+	if err != nil {
+		return nil, err
+	}
+	var body []byte
+	if headerOnly(dump) {
+		req.Body.Close()
+	} else if body, err = readBody(req.Body); err != nil {
+		return nil, fmt.Errorf("httputil: reading request body: %w", err)
+	}
+
+	req.RequestURI = ""
+	if req.URL.Host == "" {
+		req.URL.Host = req.Host
+	}
+	if req.URL.Scheme == "" {
+		req.URL.Scheme = "http"
+	}
+	if len(body) == 0 {
+		req.Body = http.NoBody
+		req.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+		return req, nil
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return req, nil
+}
+
+// LoadResponse parses dump, the HTTP/1.x wire representation of a
+// response as produced by [DumpResponse], and returns the response it
+// describes. req, which may be nil, is the request the response
+// answers; it determines whether the response can have a body, as for
+// [http.ReadResponse].
+//
+// Chunked bodies are decoded and any trailers are stored in the
+// response's Trailer field. The body is read entirely into memory.
+// A dump that ends after its header yields an empty body while
+// ContentLength keeps the declared value.
+func LoadResponse(dump []byte, req *http.Request) (*http.Response, error) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(dump)), req)
+	if err != nil {
+		return nil, err
+	}
+	var body []byte
+	if headerOnly(dump) {
+		resp.Body.Close()
+	} else if body, err = readBody(resp.Body); err != nil {
+		return nil, fmt.Errorf("httputil: reading response body: %w", err)
+	}
+	if len(body) == 0 {
+		resp.Body = http.NoBody
+	} else {
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return resp, nil
+}
+
+// An Exchange is a request together with the response it received.
+// Response may be nil for a request that was not answered.
+type Exchange struct {
+	Request  *http.Request
+	Response *http.Response
+}
+
+// Section markers of the multi-exchange dump format.
+const (
+	exchangeRequest  = "--- request "
+	exchangeResponse = "--- response "
+)
+
+// DumpExchanges writes exs to w in a multi-exchange dump format
+// suitable for golden files, which can be read back with
+// [LoadExchanges].
+//
+// Each request and response is written as by [DumpRequest] and
+// [DumpResponse] with their bodies, preceded by a marker line giving
+// the kind of message and its length in bytes:
+//
+//	--- request 41
+//	GET /path HTTP/1.1
+//	Host: example.com
+//
+//	--- response 38
+//	HTTP/1.1 200 OK
+//	Content-Length: 0
+//
+// A newline follows each message to keep the file readable. As with
+// the dump functions, bodies are consumed and replaced.
+func DumpExchanges(w io.Writer, exs []Exchange) error {
+	bw := bufio.NewWriter(w)
+	for _, ex := range exs {
+		if ex.Request == nil {
+			return errors.New("httputil: Exchange has nil Request")
+		}
+		b, err := DumpRequest(ex.Request, true)
+		if err != nil {
+			return err
+		}
+		writeSection(bw, exchangeRequest, b)
+		if ex.Response == nil {
+			continue
+		}
+		if b, err = DumpResponse(ex.Response, true); err != nil {
+			return err
+		}
+		writeSection(bw, exchangeResponse, b)
+	}
+	return bw.Flush()
+}
+
+func writeSection(w *bufio.Writer, marker string, b []byte) {
+	w.WriteString(marker)
+	w.WriteString(strconv.Itoa(len(b)))
+	w.WriteByte('\n')
+	w.Write(b)
+	w.WriteByte('\n')
+}
+
+// LoadExchanges reads exchanges written by [DumpExchanges], parsing
+// each message with [LoadRequest] or [LoadResponse].
+func LoadExchanges(r io.Reader) ([]Exchange, error) {
+	br := bufio.NewReader(r)
+	var exs []Exchange
+	for {
+		line, err := br.ReadString('\n')
+		if err == io.EOF && line == "" {
+			return exs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("httputil: reading exchange marker: %w", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			continue
+		}
+		var marker string
+		switch {
+		case strings.HasPrefix(line, exchangeRequest):
+			marker = exchangeRequest
+		case strings.HasPrefix(line, exchangeResponse):
+			marker = exchangeResponse
+		default:
+			return nil, fmt.Errorf("httputil: malformed exchange marker %q", line)
+		}
+		n, err := strconv.Atoi(line[len(marker):])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("httputil: malformed exchange marker %q", line)
+		}
+		// Read the message without trusting n to allocate: the buffer
+		// grows only as far as the input goes.
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, br, int64(n)); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("httputil: reading exchange: %w", err)
+		}
+		b := buf.Bytes()
+
+		if marker == exchangeRequest {
+			req, err := LoadRequest(b)
+			if err != nil {
+				return nil, err
+			}
+			exs = append(exs, Exchange{Request: req})
+			continue
+		}
+		if len(exs) == 0 || exs[len(exs)-1].Response != nil {
+			return nil, errors.New("httputil: response without a preceding request")
+		}
+		ex := &exs[len(exs)-1]
+		if ex.Response, err = LoadResponse(b, ex.Request); err != nil {
+			return nil, err
+		}
+	}
+}
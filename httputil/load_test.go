@@ -0,0 +1,198 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httputil
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLoadRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		dump      string
+		wantURL   string
+		wantBody  string
+		wantCL    int64
+		wantTrail http.Header
+	}{
+		{
+			name:    "get",
+			dump:    "GET /foo?x=1 HTTP/1.1\r\nHost: example.com\r\nUser-Agent: test\r\n\r\n",
+			wantURL: "http://example.com/foo?x=1",
+		},
+		{
+			name:     "post",
+			dump:     "POST /upload HTTP/1.1\r\nHost: example.com\r\nContent-Length: 5\r\n\r\nhello",
+			wantURL:  "http://example.com/upload",
+			wantBody: "hello",
+			wantCL:   5,
+		},
+		{
+			name:      "chunked with trailer",
+			dump:      "POST /c HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\nTrailer: X-Sum\r\n\r\n3\r\nabc\r\n2\r\nde\r\n0\r\nX-Sum: 42\r\n\r\n",
+			wantURL:   "http://example.com/c",
+			wantBody:  "abcde",
+			wantCL:    -1,
+			wantTrail: http.Header{"X-Sum": {"42"}},
+		},
+		{
+			name:    "header only",
+			dump:    "PUT /p HTTP/1.1\r\nHost: example.com\r\nContent-Length: 100\r\n\r\n",
+			wantURL: "http://example.com/p",
+			wantCL:  100,
+		},
+		{
+			name:    "absolute form",
+			dump:    "GET https://example.org/abs HTTP/1.1\r\nHost: example.org\r\n\r\n",
+			wantURL: "https://example.org/abs",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := LoadRequest([]byte(tt.dump))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := req.URL.String(); got != tt.wantURL {
+				t.Errorf("URL = %q; want %q", got, tt.wantURL)
+			}
+			if req.RequestURI != "" {
+				t.Errorf("RequestURI = %q; want empty", req.RequestURI)
+			}
+			if req.ContentLength != tt.wantCL {
+				t.Errorf("ContentLength = %d; want %d", req.ContentLength, tt.wantCL)
+			}
+			for i := 0; i < 2; i++ {
+				body, _ := io.ReadAll(req.Body)
+				if string(body) != tt.wantBody {
+					t.Errorf("body read %d = %q; want %q", i, body, tt.wantBody)
+				}
+				if req.Body, err = req.GetBody(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for k, v := range tt.wantTrail {
+				if got := req.Trailer.Get(k); got != v[0] {
+					t.Errorf("Trailer[%q] = %q; want %q", k, got, v[0])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadRequestDumpRoundTrip(t *testing.T) {
+	req, _ := http.NewRequest("POST", "http://example.com/x", strings.NewReader("payload"))
+	req.Header.Set("X-Test", "1")
+	dump, err := DumpRequestOut(req, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadRequest(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The Transport writes some headers in a fixed position, so
+	// compare against the server-side dump of the original wire bytes.
+	sreq, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(dump)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dump, err = DumpRequest(sreq, true); err != nil {
+		t.Fatal(err)
+	}
+	redump, err := DumpRequest(got, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dump, redump) {
+		t.Errorf("round trip mismatch:\n%s\n---\n%s", dump, redump)
+	}
+}
+
+func TestLoadResponse(t *testing.T) {
+	dump := "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\nTrailer: X-Done\r\n\r\n5\r\nhello\r\n0\r\nX-Done: yes\r\n\r\n"
+	res, err := LoadResponse([]byte(dump), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Trailer.Get("X-Done"); got != "yes" {
+		t.Errorf("trailer X-Done = %q; want %q", got, "yes")
+	}
+	redump, err := DumpResponse(res, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(redump) != dump {
+		t.Errorf("redump =\n%q\nwant\n%q", redump, dump)
+	}
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != "hello" {
+		t.Errorf("body = %q; want %q", body, "hello")
+	}
+
+	head := "HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\n"
+	res, err = LoadResponse([]byte(head), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ContentLength != 10 || res.Body != http.NoBody {
+		t.Errorf("header-only response: ContentLength = %d, Body = %T", res.ContentLength, res.Body)
+	}
+}
+
+func TestExchangesRoundTrip(t *testing.T) {
+	section := func(kind, msg string) string {
+		return fmt.Sprintf("--- %s %d\n%s\n", kind, len(msg), msg)
+	}
+	conv := section("request", "GET /path HTTP/1.1\r\nHost: example.com\r\n\r\n") +
+		section("response", "HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n") +
+		section("request", "POST /form HTTP/1.1\r\nHost: example.com\r\nContent-Length: 7\r\n\r\na=1&b=2") +
+		section("response", "HTTP/1.1 201 Created\r\nTransfer-Encoding: chunked\r\nTrailer: X-Id\r\n\r\n2\r\nok\r\n0\r\nX-Id: 7\r\n\r\n") +
+		section("request", "GET /late HTTP/1.1\r\nHost: example.com\r\n\r\n")
+
+	exs, err := LoadExchanges(strings.NewReader(conv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exs) != 3 {
+		t.Fatalf("got %d exchanges; want 3", len(exs))
+	}
+	if exs[1].Response.StatusCode != 201 || exs[1].Response.Trailer.Get("X-Id") != "7" {
+		t.Errorf("second response = %v, trailer %v", exs[1].Response.Status, exs[1].Response.Trailer)
+	}
+	if exs[2].Response != nil {
+		t.Errorf("third exchange has a response; want nil")
+	}
+
+	var buf bytes.Buffer
+	if err := DumpExchanges(&buf, exs); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != conv {
+		t.Errorf("DumpExchanges =\n%s\nwant\n%s", buf.String(), conv)
+	}
+}
+
+func TestLoadExchangesErrors(t *testing.T) {
+	tests := []string{
+		"garbage\n",
+		"--- request x\n",
+		"--- request 100\nGET / HTTP/1.1\r\n",
+		"--- request 999999999999999999\nGET / HTTP/1.1\r\n",
+		"--- request 99999999999999999999\n",
+		"--- response 38\nHTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n\n",
+	}
+	for _, in := range tests {
+		if _, err := LoadExchanges(strings.NewReader(in)); err == nil {
+			t.Errorf("LoadExchanges(%q) succeeded; want error", in)
+		}
+	}
+}
@@ -0,0 +1,536 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httputil
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPipelineDepth is the maximum number of outstanding requests
+// on a [PipelineConn] whose Depth is zero.
+const DefaultPipelineDepth = 8
+
+var (
+	// ErrConnClosed is returned by [PipelineConn.Do] once the
+	// connection has been closed, either by [PipelineConn.Close] or
+	// because the server closed it after a response.
+	ErrConnClosed = errors.New("httputil: pipelined connection closed")
+
+	// ErrSwitchedProtocols is returned by [PipelineConn.Do] after a
+	// 101 Switching Protocols response has been received.
+	ErrSwitchedProtocols = errors.New("httputil: connection switched protocols")
+
+	// errBodyNotSent is returned by the request body of an
+	// Expect: 100-continue request when the server answered before
+	// asking for the body. Request.Write does not wrap it, so
+	// continueBody also records the condition itself.
+	errBodyNotSent = errors.New("httputil: request body not sent")
+)
+
+// A PipelineConn is a persistent HTTP/1.1 client connection on which
+// requests may be pipelined: up to Depth requests are written without
+// waiting for the responses to earlier ones. It is a replacement for
+// [ClientConn.Do] intended for low-level protocol testing; for
+// everything else use [http.Client].
+//
+// Responses are matched to requests in order. A response body must be
+// read to EOF or closed before the response to the next request can be
+// delivered; closing a body discards its unread remainder.
+//
+// Each request's context governs that request. If it is canceled
+// before the request is written, the request is never sent. If it is
+// canceled while waiting for the response, Do returns the context's
+// error and the response is later read and discarded, so the
+// connection remains usable. If it is canceled while the response body
+// is being read, the connection is closed.
+//
+// Informational (1xx) responses other than 101 are reported to the
+// request's [httptrace.ClientTrace.Got1xxResponse] hook and otherwise
+// skipped. A request with an "Expect: 100-continue" header has its
+// body sent only after a 100 Continue response or after
+// ExpectContinueTimeout. A 101 Switching Protocols response is
+// returned with a Body that is an [io.ReadWriteCloser] over the
+// connection, and no further requests may be sent.
+//
+// The exported fields must not be changed after the first call to Do.
+// A PipelineConn is safe for concurrent use by multiple goroutines.
+type PipelineConn struct {
+	// Depth is the maximum number of requests that have been sent
+	// but whose responses have not been fully read. A Depth of 1
+	// disables pipelining. If zero, DefaultPipelineDepth is used.
+	Depth int
+
+	// Proxy, if true, writes requests in the form expected by an
+	// HTTP proxy, with an absolute request target.
+	Proxy bool
+
+	// ExpectContinueTimeout is the time to wait for a 100 Continue
+	// response to a request with an "Expect: 100-continue" header
+	// before sending its body anyway. If zero, the body is not sent
+	// until the server answers.
+	ExpectContinueTimeout time.Duration
+
+	// RawWrite and RawRead, if non-nil, receive a copy of every byte
+	// written to and read from the connection, respectively.
+	RawWrite io.Writer
+	RawRead  io.Writer
+
+	conn net.Conn
+	br   *bufio.Reader
+	bw   *bufio.Writer
+
+	startOnce sync.Once
+	sem       chan struct{}      // holds a token for every outstanding request
+	calls     chan *pipelineCall // requests awaiting responses, in order
+	writeMu   sync.Mutex         // serializes request writes
+	closed    chan struct{}      // closed by fail
+	rawWrites io.Writer          // conn, plus RawWrite if set
+	mu        sync.Mutex         // guards the fields below
+	err       error              // if non-nil, the connection is broken
+	writeErr  error              // if non-nil, no more requests may be written
+	failOnce  sync.Once
+}
+
+// NewPipelineConn returns a PipelineConn that sends requests on c.
+func NewPipelineConn(c net.Conn) *PipelineConn {
+	return &PipelineConn{conn: c}
+}
+
+func (pc *PipelineConn) start() {
+	pc.startOnce.Do(func() {
+		depth := pc.Depth
+		if depth <= 0 {
+			depth = DefaultPipelineDepth
+		}
+		pc.sem = make(chan struct{}, depth)
+		pc.calls = make(chan *pipelineCall, depth)
+		pc.closed = make(chan struct{})
+
+		var r io.Reader = pc.conn
+		if pc.RawRead != nil {
+			r = io.TeeReader(r, pc.RawRead)
+		}
+		pc.br = bufio.NewReader(r)
+		pc.rawWrites = pc.conn
+		if pc.RawWrite != nil {
+			pc.rawWrites = io.MultiWriter(pc.conn, pc.RawWrite)
+		}
+		pc.bw = bufio.NewWriter(pc.rawWrites)
+		go pc.readLoop()
+	})
+}
+
+// Close closes the connection. Requests that are outstanding fail
+// with [ErrConnClosed].
+func (pc *PipelineConn) Close() error {
+	pc.start()
+	pc.fail(ErrConnClosed)
+	return nil
+}
+
+// Pending returns the number of requests that have been sent but
+// whose responses have not been fully read.
+func (pc *PipelineConn) Pending() int {
+	pc.start()
+	return len(pc.sem)
+}
+
+// Do sends req on the connection, pipelined behind any outstanding
+// requests, and returns its response.
+func (pc *PipelineConn) Do(req *http.Request) (*http.Response, error) {
+	return pc.do(req, nil)
+}
+
+// DoRaw is like Do, but writes raw to the connection verbatim in
+// place of the serialized request. req describes the request for the
+// purposes of reading the response, such as its method and context;
+// its body and header are not sent. DoRaw is intended for sending
+// deliberately malformed requests in conformance tests.
+func (pc *PipelineConn) DoRaw(raw []byte, req *http.Request) (*http.Response, error) {
+	if raw == nil {
+		raw = []byte{}
+	}
+	return pc.do(req, raw)
+}
+
+func (pc *PipelineConn) do(req *http.Request, raw []byte) (*http.Response, error) {
+	pc.start()
+	ctx := req.Context()
+	select {
+	case pc.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-pc.closed:
+		return nil, pc.error()
+	}
+
+	call := &pipelineCall{
+		req:     req,
+		done:    make(chan pipelineResult, 1),
+		release: sync.OnceFunc(func() { <-pc.sem }),
+	}
+	if raw == nil && req.Body != nil && req.Body != http.NoBody &&
+		strings.EqualFold(req.Header.Get("Expect"), "100-continue") {
+		call.continueCh = make(chan bool, 1)
+	}
+	if err := pc.write(call, raw); err != nil {
+		return nil, err
+	}
+
+	select {
+	case r := <-call.done:
+		return r.res, r.err
+	case <-ctx.Done():
+		if r, ok := call.abandon(); ok {
+			return r.res, r.err
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (pc *PipelineConn) error() error {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return cmp.Or(pc.err, pc.writeErr)
+}
+
+// stopWrites prevents any further requests from being written.
+func (pc *PipelineConn) stopWrites(err error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.writeErr == nil {
+		pc.writeErr = err
+	}
+}
+
+// fail marks the connection as broken with err, closes it, and fails
+// every request awaiting a response.
+func (pc *PipelineConn) fail(err error) {
+	pc.failOnce.Do(func() {
+		pc.mu.Lock()
+		pc.err = err
+		pc.mu.Unlock()
+		pc.conn.Close()
+		close(pc.closed)
+		for {
+			select {
+			case call := <-pc.calls:
+				call.deliver(pipelineResult{err: err})
+			default:
+				return
+			}
+		}
+	})
+}
+
+// write writes call's request, or raw if non-nil, and queues call for
+// its response. On error, the request is not outstanding.
+func (pc *PipelineConn) write(call *pipelineCall, raw []byte) error {
+	pc.writeMu.Lock()
+	defer pc.writeMu.Unlock()
+
+	pc.mu.Lock()
+	if err := cmp.Or(pc.err, pc.writeErr, call.req.Context().Err()); err != nil {
+		pc.mu.Unlock()
+		call.release()
+		return err
+	}
+	// The queue has room for every holder of a sem token, so
+	// this send never blocks. Doing it under mu ensures fail
+	// sees the call.
+	pc.calls <- call
+	if call.req.Close {
+		pc.writeErr = ErrConnClosed
+	}
+	pc.mu.Unlock()
+
+	var err error
+	switch {
+	case raw != nil:
+		_, err = pc.rawWrites.Write(raw)
+	default:
+		req := call.req
+		if call.continueCh != nil {
+			r := new(http.Request)
+			*r = *req
+			r.Body = &continueBody{
+				ReadCloser: req.Body,
+				bw:         pc.bw,
+				ch:         call.continueCh,
+				timeout:    pc.ExpectContinueTimeout,
+				ctx:        req.Context(),
+			}
+			req = r
+		}
+		if pc.Proxy {
+			err = req.WriteProxy(pc.bw)
+		} else {
+			err = req.Write(pc.bw)
+		}
+		if err == nil {
+			err = pc.bw.Flush()
+		}
+		if cb, ok := req.Body.(*continueBody); ok && cb.skipped {
+			// The server has already answered without
+			// reading the body; the connection can carry no
+			// further requests.
+			pc.bw.Reset(pc.rawWrites)
+			pc.stopWrites(ErrConnClosed)
+			err = nil
+		}
+	}
+	if err != nil {
+		pc.fail(err)
+		return err
+	}
+	return nil
+}
+
+// readLoop reads responses and delivers them to calls in order.
+func (pc *PipelineConn) readLoop() {
+	for {
+		var call *pipelineCall
+		select {
+		case call = <-pc.calls:
+		case <-pc.closed:
+			return
+		}
+		err := pc.readResponse(call)
+		call.release()
+		if err == ErrSwitchedProtocols {
+			return
+		}
+		if err != nil {
+			pc.fail(err)
+			return
+		}
+	}
+}
+
+// readResponse reads the response to call, including any preceding
+// informational responses, and waits until its body is consumed.
+func (pc *PipelineConn) readResponse(call *pipelineCall) error {
+	req := call.req
+	var resp *http.Response
+	for {
+		var err error
+		resp, err = http.ReadResponse(pc.br, req)
+		if err != nil {
+			call.signalContinue(false)
+			call.deliver(pipelineResult{err: err})
+			return err
+		}
+		code := resp.StatusCode
+		if code < 100 || code > 199 || code == http.StatusSwitchingProtocols {
+			break
+		}
+		if code == http.StatusContinue {
+			call.signalContinue(true)
+		}
+		if trace := httptrace.ContextClientTrace(req.Context()); trace != nil && trace.Got1xxResponse != nil {
+			if err := trace.Got1xxResponse(code, textproto.MIMEHeader(resp.Header)); err != nil {
+				call.signalContinue(false)
+				call.deliver(pipelineResult{err: err})
+				return err
+			}
+		}
+	}
+	call.signalContinue(false)
+	if resp.Close {
+		// Refuse new requests before the caller sees the response.
+		pc.stopWrites(ErrConnClosed)
+	}
+
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		pc.stopWrites(ErrSwitchedProtocols)
+		resp.Body = &switchedBody{r: pc.br, Conn: pc.conn}
+		if !call.deliver(pipelineResult{res: resp}) {
+			pc.conn.Close()
+		}
+		return ErrSwitchedProtocols
+	}
+
+	if resp.Body == http.NoBody {
+		call.deliver(pipelineResult{res: resp})
+	} else if err := pc.awaitBody(call, resp); err != nil {
+		return err
+	}
+	if resp.Close {
+		return ErrConnClosed
+	}
+	return nil
+}
+
+// awaitBody delivers resp to call and waits until its body has been
+// consumed.
+func (pc *PipelineConn) awaitBody(call *pipelineCall, resp *http.Response) error {
+	ctx := call.req.Context()
+	body := &pipelineBody{rc: resp.Body, done: make(chan struct{}), release: call.release}
+	resp.Body = body
+	if !call.deliver(pipelineResult{res: resp}) {
+		body.Close()
+	}
+	select {
+	case <-body.done:
+		return nil
+	case <-pc.closed:
+		return pc.error()
+	case <-ctx.Done():
+	}
+	select {
+	case <-body.done:
+		// Canceled after the body was consumed.
+		return nil
+	default:
+	}
+	// The caller gave up while reading the body; the only way to
+	// unblock it and resynchronize is to close the connection.
+	pc.fail(ctx.Err())
+	return ctx.Err()
+}
+
+type pipelineResult struct {
+	res *http.Response
+	err error
+}
+
+// A pipelineCall is a request awaiting its response.
+type pipelineCall struct {
+	req        *http.Request
+	done       chan pipelineResult // buffered; receives exactly one result
+	continueCh chan bool           // if non-nil, receives the 100-continue decision
+	release    func()              // returns the call's sem token; idempotent
+
+	mu        sync.Mutex
+	abandoned bool
+	delivered bool
+}
+
+// deliver sends r to the caller. It reports false if the caller
+// abandoned the request, in which case r must be cleaned up by the
+// reader.
+func (c *pipelineCall) deliver(r pipelineResult) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.abandoned || c.delivered {
+		return false
+	}
+	c.delivered = true
+	c.done <- r
+	return true
+}
+
+// abandon marks the call as no longer wanted. If a result was
+// already delivered, it is returned instead.
+func (c *pipelineCall) abandon() (pipelineResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.delivered {
+		return <-c.done, true
+	}
+	c.abandoned = true
+	return pipelineResult{}, false
+}
+
+func (c *pipelineCall) signalContinue(send bool) {
+	if c.continueCh == nil {
+		return
+	}
+	select {
+	case c.continueCh <- send:
+	default:
+	}
+}
+
+// A continueBody delays reading a request body until the server
+// sends 100 Continue, the timeout expires, or the server answers
+// without asking for the body.
+type continueBody struct {
+	io.ReadCloser
+	bw      *bufio.Writer
+	ch      <-chan bool
+	timeout time.Duration
+	ctx     context.Context
+	decided bool
+	skipped bool // the server answered without asking for the body
+}
+
+func (b *continueBody) Read(p []byte) (int, error) {
+	if !b.decided {
+		b.decided = true
+		// Make sure the server has the header before waiting.
+		if err := b.bw.Flush(); err != nil {
+			return 0, err
+		}
+		var timer <-chan time.Time
+		if b.timeout > 0 {
+			t := time.NewTimer(b.timeout)
+			defer t.Stop()
+			timer = t.C
+		}
+		select {
+		case send := <-b.ch:
+			if !send {
+				b.skipped = true
+				return 0, errBodyNotSent
+			}
+		case <-timer:
+		case <-b.ctx.Done():
+			return 0, b.ctx.Err()
+		}
+	}
+	return b.ReadCloser.Read(p)
+}
+
+// A pipelineBody signals done when the response body has been
+// consumed, allowing the next response to be read.
+type pipelineBody struct {
+	rc      io.ReadCloser
+	once    sync.Once
+	done    chan struct{}
+	release func()
+}
+
+func (b *pipelineBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	if err != nil {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *pipelineBody) Close() error {
+	// Closing the body discards any unread remainder.
+	err := b.rc.Close()
+	b.finish()
+	return err
+}
+
+func (b *pipelineBody) finish() {
+	b.once.Do(func() {
+		b.release()
+		close(b.done)
+	})
+}
+
+// A switchedBody is the body of a 101 Switching Protocols response:
+// the connection itself, with any data already buffered.
+type switchedBody struct {
+	r *bufio.Reader
+	net.Conn
+}
+
+func (b *switchedBody) Read(p []byte) (int, error) { return b.r.Read(p) }
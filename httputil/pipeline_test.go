@@ -0,0 +1,292 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httputil
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newPipelineTest returns a PipelineConn connected to a fake server
+// running serve on the other end of an in-memory connection.
+func newPipelineTest(t *testing.T, serve func(br *bufio.Reader, c net.Conn)) *PipelineConn {
+	t.Helper()
+	cc, sc := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer sc.Close()
+		serve(bufio.NewReader(sc), sc)
+	}()
+	pc := NewPipelineConn(cc)
+	t.Cleanup(func() {
+		pc.Close()
+		<-done
+	})
+	return pc
+}
+
+func readBodyString(t *testing.T, res *http.Response) string {
+	t.Helper()
+	b, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestPipelineConnPipelines(t *testing.T) {
+	const n = 3
+	pc := newPipelineTest(t, func(br *bufio.Reader, c net.Conn) {
+		// Read every request before answering any, which only
+		// works if the client pipelines them.
+		var paths []string
+		for i := 0; i < n; i++ {
+			req, err := http.ReadRequest(br)
+			if err != nil {
+				return
+			}
+			paths = append(paths, req.URL.Path)
+		}
+		for _, p := range paths {
+			fmt.Fprintf(c, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\n\r\n%s", len(p), p)
+		}
+	})
+	pc.Depth = n
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			path := fmt.Sprintf("/%d", i)
+			req, _ := http.NewRequest("GET", "http://example.com"+path, nil)
+			res, err := pc.Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if got := readBodyString(t, res); got != path {
+				t.Errorf("response to %s = %q", path, got)
+			}
+		}()
+	}
+	wg.Wait()
+	if p := pc.Pending(); p != 0 {
+		t.Errorf("Pending = %d after all responses; want 0", p)
+	}
+}
+
+func TestPipelineConnInformational(t *testing.T) {
+	pc := newPipelineTest(t, func(br *bufio.Reader, c net.Conn) {
+		if _, err := http.ReadRequest(br); err != nil {
+			return
+		}
+		io.WriteString(c, "HTTP/1.1 103 Early Hints\r\nLink: </style.css>; rel=preload\r\n\r\n"+
+			"HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")
+	})
+	var got []string
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			got = append(got, fmt.Sprint(code, " ", header.Get("Link")))
+			return nil
+		},
+	})
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com/", nil)
+	res, err := pc.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := readBodyString(t, res); res.StatusCode != 200 || body != "ok" {
+		t.Errorf("response = %d %q", res.StatusCode, body)
+	}
+	if want := "103 </style.css>; rel=preload"; len(got) != 1 || got[0] != want {
+		t.Errorf("Got1xxResponse calls = %q; want [%q]", got, want)
+	}
+}
+
+func TestPipelineConnExpectContinue(t *testing.T) {
+	pc := newPipelineTest(t, func(br *bufio.Reader, c net.Conn) {
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			return
+		}
+		io.WriteString(c, "HTTP/1.1 100 Continue\r\n\r\n")
+		b, _ := io.ReadAll(req.Body)
+		fmt.Fprintf(c, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\n\r\n%s", len(b), b)
+
+		// Reject the second request without reading its body.
+		if _, err := http.ReadRequest(br); err != nil {
+			return
+		}
+		io.WriteString(c, "HTTP/1.1 417 Expectation Failed\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+	})
+
+	req, _ := http.NewRequest("PUT", "http://example.com/", strings.NewReader("body"))
+	req.Header.Set("Expect", "100-continue")
+	res, err := pc.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readBodyString(t, res); got != "body" {
+		t.Errorf("echoed body = %q; want %q", got, "body")
+	}
+
+	req, _ = http.NewRequest("PUT", "http://example.com/", strings.NewReader("unsent"))
+	req.Header.Set("Expect", "100-continue")
+	res, err = pc.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusExpectationFailed {
+		t.Errorf("status = %d; want 417", res.StatusCode)
+	}
+	req, _ = http.NewRequest("GET", "http://example.com/", nil)
+	if _, err := pc.Do(req); !errors.Is(err, ErrConnClosed) {
+		t.Errorf("Do after rejected body = %v; want ErrConnClosed", err)
+	}
+}
+
+func TestPipelineConnCancelWaiting(t *testing.T) {
+	release := make(chan struct{})
+	pc := newPipelineTest(t, func(br *bufio.Reader, c net.Conn) {
+		for i := 0; i < 2; i++ {
+			if _, err := http.ReadRequest(br); err != nil {
+				return
+			}
+		}
+		<-release
+		io.WriteString(c, "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nfirst")
+		io.WriteString(c, "HTTP/1.1 200 OK\r\nContent-Length: 6\r\n\r\nsecond")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com/1", nil)
+		_, err := pc.Do(req)
+		errc <- err
+	}()
+	for pc.Pending() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	resc := make(chan *http.Response, 1)
+	go func() {
+		req, _ := http.NewRequest("GET", "http://example.com/2", nil)
+		res, err := pc.Do(req)
+		if err != nil {
+			t.Error(err)
+		}
+		resc <- res
+	}()
+	for pc.Pending() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled Do = %v; want context.Canceled", err)
+	}
+	close(release)
+	res := <-resc
+	if res == nil {
+		return
+	}
+	if got := readBodyString(t, res); got != "second" {
+		t.Errorf("second response = %q; want %q", got, "second")
+	}
+}
+
+func TestPipelineConnRaw(t *testing.T) {
+	pc := newPipelineTest(t, func(br *bufio.Reader, c net.Conn) {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return
+		}
+		br.ReadString('\n') // blank line
+		fmt.Fprintf(c, "HTTP/1.1 400 Bad Request\r\nContent-Length: %d\r\n\r\n%s", len(line), line)
+	})
+	var wrote, read bytes.Buffer
+	pc.RawWrite = &wrote
+	pc.RawRead = &read
+
+	const raw = "GET /\x00bad HTTP/1.1\r\n\r\n"
+	req, _ := http.NewRequest("GET", "http://example.com/", nil)
+	res, err := pc.DoRaw([]byte(raw), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readBodyString(t, res); got != "GET /\x00bad HTTP/1.1\r\n" {
+		t.Errorf("server saw %q", got)
+	}
+	if wrote.String() != raw {
+		t.Errorf("RawWrite = %q; want %q", wrote.String(), raw)
+	}
+	if !strings.HasPrefix(read.String(), "HTTP/1.1 400 Bad Request\r\n") {
+		t.Errorf("RawRead = %q", read.String())
+	}
+}
+
+func TestPipelineConnSwitchingProtocols(t *testing.T) {
+	pc := newPipelineTest(t, func(br *bufio.Reader, c net.Conn) {
+		if _, err := http.ReadRequest(br); err != nil {
+			return
+		}
+		io.WriteString(c, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		io.Copy(c, br)
+	})
+	req, _ := http.NewRequest("GET", "http://example.com/", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "echo")
+	res, err := pc.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rwc, ok := res.Body.(io.ReadWriteCloser)
+	if !ok {
+		t.Fatalf("101 response Body is %T; want io.ReadWriteCloser", res.Body)
+	}
+	go io.WriteString(rwc, "ping")
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(rwc, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("echo = %q, %v; want %q", buf, err, "ping")
+	}
+	req, _ = http.NewRequest("GET", "http://example.com/", nil)
+	if _, err := pc.Do(req); !errors.Is(err, ErrSwitchedProtocols) {
+		t.Errorf("Do after upgrade = %v; want ErrSwitchedProtocols", err)
+	}
+	rwc.Close()
+}
+
+func TestPipelineConnServerClose(t *testing.T) {
+	pc := newPipelineTest(t, func(br *bufio.Reader, c net.Conn) {
+		if _, err := http.ReadRequest(br); err != nil {
+			return
+		}
+		io.WriteString(c, "HTTP/1.1 200 OK\r\nContent-Length: 3\r\nConnection: close\r\n\r\nbye")
+	})
+	req, _ := http.NewRequest("GET", "http://example.com/", nil)
+	res, err := pc.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readBodyString(t, res)
+	req, _ = http.NewRequest("GET", "http://example.com/", nil)
+	if _, err := pc.Do(req); !errors.Is(err, ErrConnClosed) {
+		t.Errorf("Do after Connection: close = %v; want ErrConnClosed", err)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgienv
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+// CopyFlush copies the gateway program's output in r to rw, flushing
+// after each read so that output the program has flushed reaches the
+// client promptly.
+func CopyFlush(rw http.ResponseWriter, r io.Reader) error {
+	rc := http.NewResponseController(rw)
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, err := rw.Write(buf[:n]); err != nil {
+				return err
+			}
+			if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,334 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Range requests, RFC 9110 § 14, for content generated on the fly.
+
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// A ByteRange is a range of bytes of a representation.
+type ByteRange struct {
+	Start, Length int64
+}
+
+// ContentRange returns the value of the Content-Range header for r in
+// a representation of the given size, such as "bytes 0-499/1234".
+func (r ByteRange) ContentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.Start, r.Start+r.Length-1, size)
+}
+
+func (r ByteRange) mimeHeader(contentType string, size int64) textproto.MIMEHeader {
+	h := textproto.MIMEHeader{"Content-Range": {r.ContentRange(size)}}
+	if contentType != "" {
+		h.Set("Content-Type", contentType)
+	}
+	return h
+}
+
+// ErrNoOverlap is returned by [ParseRange] if none of the ranges it
+// parses selects a byte of the representation: each starts beyond
+// its end or is a suffix range of no bytes.
+var ErrNoOverlap = errors.New("invalid range: failed to overlap")
+
+// ParseRange parses the value s of a Range header for a representation
+// of the given size, returning the ranges in the order requested.
+// Suffix ranges, such as "bytes=-500", and ranges extending past the
+// end are limited to the representation; ranges starting past the end
+// and suffix ranges of no bytes are dropped. An empty s returns no
+// ranges and no error.
+//
+// ParseRange returns [ErrNoOverlap] if no range overlaps the
+// representation, and another error if s is malformed.
+func ParseRange(s string, size int64) ([]ByteRange, error) {
+	if s == "" {
+		return nil, nil // header not present
+	}
+	const b = "bytes="
+	if !strings.HasPrefix(s, b) {
+		return nil, errors.New("invalid range")
+	}
+	var ranges []ByteRange
+	noOverlap := false
+	for ra := range strings.SplitSeq(s[len(b):], ",") {
+		ra = textproto.TrimString(ra)
+		if ra == "" {
+			continue
+		}
+		start, end, ok := strings.Cut(ra, "-")
+		if !ok {
+			return nil, errors.New("invalid range")
+		}
+		start, end = textproto.TrimString(start), textproto.TrimString(end)
+		var r ByteRange
+		if start == "" {
+			// If no start is specified, end specifies the
+			// range start relative to the end of the file,
+			// and we are dealing with <suffix-length>
+			// which has to be a non-negative integer as per
+			// RFC 7233 Section 2.1 "Byte-Ranges".
+			if end == "" || end[0] == '-' {
+				return nil, errors.New("invalid range")
+			}
+			i, err := strconv.ParseInt(end, 10, 64)
+			if i < 0 || err != nil {
+				return nil, errors.New("invalid range")
+			}
+			if i > size {
+				i = size
+			}
+			if i == 0 {
+				// A suffix of no bytes, or of an empty
+				// representation, selects nothing (RFC 9110
+				// Section 14.1.3).
+				noOverlap = true
+				continue
+			}
+			r.Start = size - i
+			r.Length = size - r.Start
+		} else {
+			i, err := strconv.ParseInt(start, 10, 64)
+			if err != nil || i < 0 {
+				return nil, errors.New("invalid range")
+			}
+			if i >= size {
+				// If the range begins after the size of the content,
+				// then it does not overlap.
+				noOverlap = true
+				continue
+			}
+			r.Start = i
+			if end == "" {
+				// If no end is specified, range extends to end of the file.
+				r.Length = size - r.Start
+			} else {
+				i, err := strconv.ParseInt(end, 10, 64)
+				if err != nil || r.Start > i {
+					return nil, errors.New("invalid range")
+				}
+				if i >= size {
+					i = size - 1
+				}
+				r.Length = i - r.Start + 1
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	if noOverlap && len(ranges) == 0 {
+		// The specified ranges did not overlap with the content.
+		return nil, ErrNoOverlap
+	}
+	return ranges, nil
+}
+
+// A RangeWriter writes the response to a request for a representation
+// that a handler produces on the fly, such as an object fetched from
+// storage with ranged reads, honoring the request's Range and If-Range
+// headers as [ServeContent] does for an [io.ReadSeeker]:
+//
+//	w.Header().Set("Content-Type", obj.ContentType)
+//	w.Header().Set("ETag", obj.ETag)
+//	rw, err := http.NewRangeWriter(w, r, obj.Size)
+//	if err != nil {
+//		return // the response has been sent
+//	}
+//	for _, br := range rw.Ranges() {
+//		body, err := obj.ReadRange(ctx, br.Start, br.Length)
+//		if err != nil {
+//			return
+//		}
+//		err = rw.WriteRange(br, body)
+//		body.Close()
+//		if err != nil {
+//			return
+//		}
+//	}
+//	rw.Close()
+type RangeWriter struct {
+	w           ResponseWriter
+	size        int64
+	contentType string
+	ranges      []ByteRange
+	next        int               // index in ranges of the next range to write
+	mw          *multipart.Writer // for a multipart/byteranges response, or nil
+}
+
+// NewRangeWriter writes the header of the response to req for a
+// representation of the given size, whose Content-Type, ETag and
+// Last-Modified headers, if known, the handler has set in w.Header().
+//
+// For a GET request with a satisfiable Range header whose If-Range
+// header, if any, matches the ETag or Last-Modified header, the
+// response is a 206 (Partial Content) with a single range, or a
+// multipart/byteranges body for several. Otherwise it is a 200 (OK)
+// with the whole representation, as one range.
+//
+// If the Range header is malformed or not satisfiable, NewRangeWriter
+// replies with 416 (Range Not Satisfiable) and a Content-Range header
+// giving the size, and returns the error.
+//
+// A negative size means the size is unknown: Range headers are then
+// ignored and the response has no Content-Length.
+func NewRangeWriter(w ResponseWriter, req *Request, size int64) (*RangeWriter, error) {
+	h := w.Header()
+	rw := &RangeWriter{
+		w:           w,
+		size:        size,
+		contentType: h.Get("Content-Type"),
+	}
+	var ranges []ByteRange
+	if rangeReq := req.Header.Get("Range"); size >= 0 && rangeReq != "" && req.Method == "GET" && ifRangeMatches(h, req) {
+		var err error
+		ranges, err = ParseRange(rangeReq, size)
+		if err != nil {
+			h.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			Error(w, err.Error(), StatusRequestedRangeNotSatisfiable)
+			return nil, err
+		}
+		var total int64
+		for _, r := range ranges {
+			total += r.Length
+		}
+		if total > size {
+			// The total number of bytes in all the ranges is
+			// larger than the size of the representation, so the
+			// client is wasting resources: send it whole.
+			ranges = nil
+		}
+	}
+	if size >= 0 {
+		h.Set("Accept-Ranges", "bytes")
+	}
+
+	code := StatusOK
+	switch {
+	case len(ranges) == 1:
+		code = StatusPartialContent
+		h.Set("Content-Range", ranges[0].ContentRange(size))
+		h.Set("Content-Length", strconv.FormatInt(ranges[0].Length, 10))
+	case len(ranges) > 1:
+		code = StatusPartialContent
+		rw.mw = multipart.NewWriter(w)
+		rw.mw.SetBoundary(randomBoundary())
+		h.Set("Content-Type", "multipart/byteranges; boundary="+rw.mw.Boundary())
+		h.Set("Content-Length", strconv.FormatInt(rw.multipartSize(ranges), 10))
+	case size >= 0:
+		ranges = []ByteRange{{0, size}}
+		h.Set("Content-Length", strconv.FormatInt(size, 10))
+	default:
+		ranges = []ByteRange{{0, -1}}
+	}
+	w.WriteHeader(code)
+	if req.Method != "HEAD" {
+		rw.ranges = ranges
+	}
+	return rw, nil
+}
+
+// ifRangeMatches reports whether the If-Range header of req, if any,
+// matches the representation described by h, so that the Range header
+// applies.
+func ifRangeMatches(h Header, req *Request) bool {
+	ir := req.Header.Get("If-Range")
+	if ir == "" {
+		return true
+	}
+	if strings.HasPrefix(ir, `"`) || strings.HasPrefix(ir, "W/") {
+		// An entity tag, which must match strongly.
+		etag := h.Get("ETag")
+		return !strings.HasPrefix(ir, "W/") && !strings.HasPrefix(etag, "W/") && ir == etag
+	}
+	lm := h.Get("Last-Modified")
+	if lm == "" {
+		return false
+	}
+	t, err := ParseTime(ir)
+	if err != nil {
+		return false
+	}
+	modtime, err := ParseTime(lm)
+	return err == nil && t.Equal(modtime)
+}
+
+// multipartSize returns the length of the multipart/byteranges body
+// with ranges.
+func (rw *RangeWriter) multipartSize(ranges []ByteRange) int64 {
+	var w countingWriter
+	mw := multipart.NewWriter(&w)
+	mw.SetBoundary(rw.mw.Boundary())
+	for _, r := range ranges {
+		mw.CreatePart(r.mimeHeader(rw.contentType, rw.size))
+		w += countingWriter(r.Length)
+	}
+	mw.Close()
+	return int64(w)
+}
+
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
+
+func randomBoundary() string {
+	var buf [30]byte
+	rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
+
+// Ranges returns the ranges of the representation the handler must
+// write, in order, with WriteRange. It returns nil for a HEAD request
+// or if NewRangeWriter returned an error. A range of the whole
+// representation whose size is unknown has a Length of -1.
+func (rw *RangeWriter) Ranges() []ByteRange {
+	return rw.ranges
+}
+
+// WriteRange writes the bytes of r, the next of the ranges returned by
+// Ranges, read from content, which must hold exactly those bytes.
+func (rw *RangeWriter) WriteRange(r ByteRange, content io.Reader) error {
+	if rw.next >= len(rw.ranges) || rw.ranges[rw.next] != r {
+		return fmt.Errorf("http: RangeWriter: range %d-%d written out of order", r.Start, r.Start+r.Length-1)
+	}
+	rw.next++
+	var w io.Writer = rw.w
+	if rw.mw != nil {
+		var err error
+		if w, err = rw.mw.CreatePart(r.mimeHeader(rw.contentType, rw.size)); err != nil {
+			return err
+		}
+	}
+	if r.Length < 0 {
+		_, err := io.Copy(w, content)
+		return err
+	}
+	n, err := io.CopyN(w, content, r.Length)
+	if err == io.EOF {
+		return fmt.Errorf("http: RangeWriter: content of range %d-%d is short by %d bytes: %w", r.Start, r.Start+r.Length-1, r.Length-n, io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+// Close completes the response, ending a multipart body. It returns an
+// error if some of the ranges were not written.
+func (rw *RangeWriter) Close() error {
+	if rw.next < len(rw.ranges) {
+		return fmt.Errorf("http: RangeWriter closed with %d of %d ranges written", rw.next, len(rw.ranges))
+	}
+	if rw.mw != nil {
+		return rw.mw.Close()
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/johnsiilver/http/httptest"
+)
+
+func TestParseRange(t *testing.T) {
+	for _, tt := range []struct {
+		s       string
+		size    int64
+		want    []ByteRange
+		wantErr error
+		bad     bool
+	}{
+		{s: "", size: 10},
+		{s: "bytes=0-4", size: 10, want: []ByteRange{{0, 5}}},
+		{s: "bytes=2-", size: 10, want: []ByteRange{{2, 8}}},
+		{s: "bytes=-3", size: 10, want: []ByteRange{{7, 3}}},
+		{s: "bytes=-20", size: 10, want: []ByteRange{{0, 10}}},
+		{s: "bytes=5-100", size: 10, want: []ByteRange{{5, 5}}},
+		{s: "bytes=0-0, 8-9", size: 10, want: []ByteRange{{0, 1}, {8, 2}}},
+		{s: "bytes=0-1, 20-30", size: 10, want: []ByteRange{{0, 2}}},
+		{s: "bytes=10-", size: 10, wantErr: ErrNoOverlap},
+		{s: "bytes=10-20, 30-", size: 10, wantErr: ErrNoOverlap},
+		{s: "bytes=-0", size: 10, wantErr: ErrNoOverlap},
+		{s: "bytes=-5", size: 0, wantErr: ErrNoOverlap},
+		{s: "bytes=-0, 2-3", size: 10, want: []ByteRange{{2, 2}}},
+		{s: "bytes 0-4", size: 10, bad: true},
+		{s: "bytes=4-2", size: 10, bad: true},
+		{s: "bytes=x-2", size: 10, bad: true},
+		{s: "bytes=--2", size: 10, bad: true},
+		{s: "bytes=-", size: 10, bad: true},
+		{s: "bytes=5", size: 10, bad: true},
+	} {
+		got, err := ParseRange(tt.s, tt.size)
+		switch {
+		case tt.bad:
+			if err == nil || err == ErrNoOverlap {
+				t.Errorf("ParseRange(%q, %d) error = %v; want invalid range", tt.s, tt.size, err)
+			}
+		case err != tt.wantErr:
+			t.Errorf("ParseRange(%q, %d) error = %v; want %v", tt.s, tt.size, err, tt.wantErr)
+		case !slices.Equal(got, tt.want):
+			t.Errorf("ParseRange(%q, %d) = %v; want %v", tt.s, tt.size, got, tt.want)
+		}
+	}
+}
+
+const rangeContent = "0123456789"
+
+// serveRange serves rangeContent to req with a RangeWriter, and returns
+// the recorded response.
+func serveRange(t *testing.T, req *Request, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Type", "text/plain")
+	for k, v := range header {
+		rec.Header().Set(k, v)
+	}
+	rw, err := NewRangeWriter(rec, req, int64(len(rangeContent)))
+	if err != nil {
+		return rec
+	}
+	for _, r := range rw.Ranges() {
+		if err := rw.WriteRange(r, strings.NewReader(rangeContent[r.Start:r.Start+r.Length])); err != nil {
+			t.Fatalf("WriteRange(%v): %v", r, err)
+		}
+	}
+	if err := rw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return rec
+}
+
+func TestRangeWriter(t *testing.T) {
+	const (
+		etag    = `"v1"`
+		modtime = "Mon, 02 Jan 2006 15:04:05 GMT"
+	)
+	for _, tt := range []struct {
+		name         string
+		method       string
+		reqHeader    map[string]string
+		wantCode     int
+		wantBody     string
+		wantRange    string
+		wantAccept   bool
+		wantNoLength bool
+	}{
+		{
+			name:       "whole",
+			wantCode:   StatusOK,
+			wantBody:   rangeContent,
+			wantAccept: true,
+		},
+		{
+			name:       "single",
+			reqHeader:  map[string]string{"Range": "bytes=2-4"},
+			wantCode:   StatusPartialContent,
+			wantBody:   "234",
+			wantRange:  "bytes 2-4/10",
+			wantAccept: true,
+		},
+		{
+			name:       "suffix",
+			reqHeader:  map[string]string{"Range": "bytes=-2"},
+			wantCode:   StatusPartialContent,
+			wantBody:   "89",
+			wantRange:  "bytes 8-9/10",
+			wantAccept: true,
+		},
+		{
+			name:       "unsatisfiable",
+			reqHeader:  map[string]string{"Range": "bytes=20-"},
+			wantCode:   StatusRequestedRangeNotSatisfiable,
+			wantRange:  "bytes */10",
+			wantBody:   "invalid range: failed to overlap\n",
+			wantAccept: false,
+		},
+		{
+			name:       "empty suffix",
+			reqHeader:  map[string]string{"Range": "bytes=-0"},
+			wantCode:   StatusRequestedRangeNotSatisfiable,
+			wantRange:  "bytes */10",
+			wantBody:   "invalid range: failed to overlap\n",
+			wantAccept: false,
+		},
+		{
+			name:       "malformed",
+			reqHeader:  map[string]string{"Range": "bytes=4-2"},
+			wantCode:   StatusRequestedRangeNotSatisfiable,
+			wantRange:  "bytes */10",
+			wantBody:   "invalid range\n",
+			wantAccept: false,
+		},
+		{
+			name:       "post ignores range",
+			method:     "POST",
+			reqHeader:  map[string]string{"Range": "bytes=2-4"},
+			wantCode:   StatusOK,
+			wantBody:   rangeContent,
+			wantAccept: true,
+		},
+		{
+			name:       "head",
+			method:     "HEAD",
+			wantCode:   StatusOK,
+			wantAccept: true,
+		},
+		{
+			name:       "if-range etag matches",
+			reqHeader:  map[string]string{"Range": "bytes=0-0", "If-Range": etag},
+			wantCode:   StatusPartialContent,
+			wantBody:   "0",
+			wantRange:  "bytes 0-0/10",
+			wantAccept: true,
+		},
+		{
+			name:       "if-range etag differs",
+			reqHeader:  map[string]string{"Range": "bytes=0-0", "If-Range": `"v0"`},
+			wantCode:   StatusOK,
+			wantBody:   rangeContent,
+			wantAccept: true,
+		},
+		{
+			name:       "if-range weak etag",
+			reqHeader:  map[string]string{"Range": "bytes=0-0", "If-Range": "W/" + etag},
+			wantCode:   StatusOK,
+			wantBody:   rangeContent,
+			wantAccept: true,
+		},
+		{
+			name:       "if-range date matches",
+			reqHeader:  map[string]string{"Range": "bytes=0-0", "If-Range": modtime},
+			wantCode:   StatusPartialContent,
+			wantBody:   "0",
+			wantRange:  "bytes 0-0/10",
+			wantAccept: true,
+		},
+		{
+			name:       "if-range date differs",
+			reqHeader:  map[string]string{"Range": "bytes=0-0", "If-Range": "Tue, 03 Jan 2006 15:04:05 GMT"},
+			wantCode:   StatusOK,
+			wantBody:   rangeContent,
+			wantAccept: true,
+		},
+		{
+			name:       "overlapping ranges too large",
+			reqHeader:  map[string]string{"Range": "bytes=0-9, 0-9"},
+			wantCode:   StatusOK,
+			wantBody:   rangeContent,
+			wantAccept: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = "GET"
+			}
+			req := httptest.NewRequest(method, "/", nil)
+			for k, v := range tt.reqHeader {
+				req.Header.Set(k, v)
+			}
+			rec := serveRange(t, req, map[string]string{"ETag": etag, "Last-Modified": modtime})
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d; want %d", rec.Code, tt.wantCode)
+			}
+			if got := rec.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q; want %q", got, tt.wantBody)
+			}
+			if got := rec.Header().Get("Content-Range"); got != tt.wantRange {
+				t.Errorf("Content-Range = %q; want %q", got, tt.wantRange)
+			}
+			if got := rec.Header().Get("Accept-Ranges") == "bytes"; got != tt.wantAccept {
+				t.Errorf("Accept-Ranges: bytes = %v; want %v", got, tt.wantAccept)
+			}
+			if tt.wantCode == StatusOK || tt.wantCode == StatusPartialContent {
+				want := len(tt.wantBody)
+				if method == "HEAD" {
+					want = len(rangeContent)
+				}
+				if got := rec.Header().Get("Content-Length"); got != strconv.Itoa(want) {
+					t.Errorf("Content-Length = %q; want %d", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRangeWriterMultipart(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Range", "bytes=0-1, 5-6, -1")
+	rec := serveRange(t, req, nil)
+	if rec.Code != StatusPartialContent {
+		t.Fatalf("status = %d; want %d", rec.Code, StatusPartialContent)
+	}
+	if got, want := rec.Header().Get("Content-Length"), strconv.Itoa(rec.Body.Len()); got != want {
+		t.Errorf("Content-Length = %s; body has %s bytes", got, want)
+	}
+	mt, params, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	if err != nil || mt != "multipart/byteranges" {
+		t.Fatalf("Content-Type = %q; want multipart/byteranges", rec.Header().Get("Content-Type"))
+	}
+	mr := multipart.NewReader(rec.Body, params["boundary"])
+	for _, want := range []struct{ body, rng string }{
+		{"01", "bytes 0-1/10"},
+		{"56", "bytes 5-6/10"},
+		{"9", "bytes 9-9/10"},
+	} {
+		p, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("NextPart: %v", err)
+		}
+		body, _ := io.ReadAll(p)
+		if string(body) != want.body {
+			t.Errorf("part body = %q; want %q", body, want.body)
+		}
+		if got := p.Header.Get("Content-Range"); got != want.rng {
+			t.Errorf("part Content-Range = %q; want %q", got, want.rng)
+		}
+		if got := p.Header.Get("Content-Type"); got != "text/plain" {
+			t.Errorf("part Content-Type = %q; want text/plain", got)
+		}
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Errorf("NextPart after last part: %v; want EOF", err)
+	}
+}
+
+func TestRangeWriterErrors(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Range", "bytes=0-1, 5-6")
+	rw, err := NewRangeWriter(httptest.NewRecorder(), req, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rw.WriteRange(ByteRange{5, 2}, strings.NewReader("56")); err == nil {
+		t.Error("WriteRange out of order succeeded")
+	}
+	if err := rw.WriteRange(ByteRange{0, 2}, strings.NewReader("0")); err == nil || !strings.Contains(err.Error(), "short") {
+		t.Errorf("WriteRange with short content: %v; want short content error", err)
+	}
+	if err := rw.Close(); err == nil {
+		t.Error("Close with a range unwritten succeeded")
+	}
+
+	rec := httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Range", "bytes=-5")
+	if _, err := NewRangeWriter(rec, req, 0); err != ErrNoOverlap {
+		t.Errorf("NewRangeWriter for a suffix of an empty representation: %v; want ErrNoOverlap", err)
+	}
+	if rec.Code != StatusRequestedRangeNotSatisfiable || rec.Header().Get("Content-Range") != "bytes */0" {
+		t.Errorf("empty representation: status %d, Content-Range %q; want 416, bytes */0", rec.Code, rec.Header().Get("Content-Range"))
+	}
+
+	rec = httptest.NewRecorder()
+	rw, err = NewRangeWriter(rec, httptest.NewRequest("GET", "/", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rw.Ranges(); !slices.Equal(got, []ByteRange{{0, -1}}) {
+		t.Fatalf("Ranges() for unknown size = %v", got)
+	}
+	if err := rw.WriteRange(rw.Ranges()[0], strings.NewReader(rangeContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Body.String() != rangeContent || rec.Header().Get("Content-Length") != "" || rec.Header().Get("Accept-Ranges") != "" {
+		t.Errorf("unknown size: body %q, header %v", rec.Body, rec.Header())
+	}
+}
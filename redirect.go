@@ -0,0 +1,251 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Redirect policies for Client.CheckRedirect.
+
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// A RedirectPolicy decides whether a [Client] follows a redirect.
+// It has the signature of [Client.CheckRedirect], to which it can be
+// assigned directly:
+//
+//	c := &http.Client{
+//		CheckRedirect: http.ComposeRedirectPolicies(
+//			http.MaxRedirects(5),
+//			http.NoDowngradeRedirects(),
+//			http.StripSensitiveHeaders(),
+//		),
+//	}
+//
+// req is the upcoming request and via holds the requests made so far,
+// oldest first. A policy returning a non-nil error stops the
+// redirect; the Client then returns that error wrapped in a
+// [*url.Error].
+type RedirectPolicy func(req *Request, via []*Request) error
+
+// ErrRedirectBlocked is wrapped by the errors returned by the
+// policies in this package when they refuse a redirect.
+var ErrRedirectBlocked = errors.New("http: redirect blocked by policy")
+
+func blockRedirect(req *Request, reason string) error {
+	return fmt.Errorf("%w: %s to %s", ErrRedirectBlocked, reason, req.URL.Redacted())
+}
+
+// ComposeRedirectPolicies returns a RedirectPolicy that applies
+// policies in order and returns the first non-nil error. Policies
+// that modify the request, such as [StripSensitiveHeaders], see the
+// changes made by the policies before them.
+//
+// Unlike the Client's default policy, the result places no limit on
+// the number of redirects unless [MaxRedirects] is among policies.
+func ComposeRedirectPolicies(policies ...RedirectPolicy) RedirectPolicy {
+	return func(req *Request, via []*Request) error {
+		for _, p := range policies {
+			if err := p(req, via); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// MaxRedirects returns a RedirectPolicy that stops after n redirects.
+func MaxRedirects(n int) RedirectPolicy {
+	return func(req *Request, via []*Request) error {
+		if len(via) > n {
+			return blockRedirect(req, fmt.Sprintf("stopped after %d redirects", n))
+		}
+		return nil
+	}
+}
+
+// SameHostRedirects returns a RedirectPolicy that only follows
+// redirects to the host of the original request. Hosts are compared
+// case-insensitively, ignoring the port.
+func SameHostRedirects() RedirectPolicy {
+	return func(req *Request, via []*Request) error {
+		if !strings.EqualFold(req.URL.Hostname(), via[0].URL.Hostname()) {
+			return blockRedirect(req, "cross-host redirect")
+		}
+		return nil
+	}
+}
+
+// SameSchemeRedirects returns a RedirectPolicy that only follows
+// redirects using the scheme of the original request.
+func SameSchemeRedirects() RedirectPolicy {
+	return func(req *Request, via []*Request) error {
+		if !strings.EqualFold(req.URL.Scheme, via[0].URL.Scheme) {
+			return blockRedirect(req, "cross-scheme redirect")
+		}
+		return nil
+	}
+}
+
+// NoDowngradeRedirects returns a RedirectPolicy that refuses to
+// follow a redirect from an https URL to an http one. Unlike
+// [SameSchemeRedirects], it allows upgrades from http to https.
+func NoDowngradeRedirects() RedirectPolicy {
+	return func(req *Request, via []*Request) error {
+		last := via[len(via)-1].URL
+		if strings.EqualFold(last.Scheme, "https") && strings.EqualFold(req.URL.Scheme, "http") {
+			return blockRedirect(req, "https to http downgrade")
+		}
+		return nil
+	}
+}
+
+// DefaultSensitiveHeaders lists the headers removed by
+// [StripSensitiveHeaders] when called without arguments.
+var DefaultSensitiveHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"WWW-Authenticate",
+}
+
+// StripSensitiveHeaders returns a RedirectPolicy that removes the
+// named headers from a redirected request whose origin (scheme, host
+// and port) differs from that of the original request. If no names
+// are given, [DefaultSensitiveHeaders] is used.
+//
+// The Client already drops these headers on redirects to a domain
+// that is not the original one or a subdomain of it; this policy is
+// stricter and also drops them on redirects to a subdomain, another
+// port or another scheme. Cookies added by the Client's Jar are not
+// affected, since the Jar applies its own origin rules.
+func StripSensitiveHeaders(names ...string) RedirectPolicy {
+	if len(names) == 0 {
+		names = DefaultSensitiveHeaders
+	}
+	return func(req *Request, via []*Request) error {
+		if sameOrigin(req.URL, via[0].URL) {
+			return nil
+		}
+		for _, name := range names {
+			req.Header.Del(name)
+		}
+		return nil
+	}
+}
+
+// sameOrigin reports whether a and b have the same scheme, host and
+// port, as in RFC 6454.
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(a.Hostname(), b.Hostname()) &&
+		originPort(a) == originPort(b)
+}
+
+func originPort(u *url.URL) string {
+	if p := u.Port(); p != "" {
+		return p
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+// A RedirectHop describes one request in a chain of redirects.
+type RedirectHop struct {
+	Method string
+	URL    *url.URL
+
+	// StatusCode is the status of the redirect response that led
+	// to this request, or zero for the original request.
+	StatusCode int
+}
+
+// String returns the hop in a form suitable for logging, with any
+// password in the URL redacted.
+func (h RedirectHop) String() string {
+	if h.StatusCode == 0 {
+		return h.Method + " " + h.URL.Redacted()
+	}
+	return fmt.Sprintf("%d -> %s %s", h.StatusCode, h.Method, h.URL.Redacted())
+}
+
+type redirectChainKey struct{}
+
+// redirectChain holds the hops recorded by RecordRedirects.
+type redirectChain struct {
+	mu   sync.Mutex
+	hops []RedirectHop
+}
+
+// WithRedirectChain returns a copy of ctx in which [RecordRedirects]
+// records the redirects followed by requests made with it. Since the
+// Client sends redirected requests with the context of the original
+// request, the chain can be read back from the context of the final
+// response's request with [RedirectChainFromContext]:
+//
+//	req, _ := http.NewRequestWithContext(http.WithRedirectChain(ctx), "GET", url, nil)
+//	resp, err := client.Do(req)
+//	log.Print(http.RedirectChainFromContext(req.Context()))
+//
+// The chain is available even if Do returns an error.
+func WithRedirectChain(ctx context.Context) context.Context {
+	return context.WithValue(ctx, redirectChainKey{}, new(redirectChain))
+}
+
+// RedirectChainFromContext returns the redirect chain recorded in
+// ctx, starting with the original request and ending with the last
+// redirect the Client was asked to follow. It returns nil if ctx was
+// not created by [WithRedirectChain] or no redirect has been seen.
+func RedirectChainFromContext(ctx context.Context) []RedirectHop {
+	rc, _ := ctx.Value(redirectChainKey{}).(*redirectChain)
+	if rc == nil {
+		return nil
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]RedirectHop(nil), rc.hops...)
+}
+
+// RecordRedirects returns a RedirectPolicy that records each redirect
+// in the chain stored in the request's context by
+// [WithRedirectChain]. It never refuses a redirect, and does nothing
+// for requests whose context has no chain. Place it first when
+// composing policies so that refused redirects are recorded too.
+func RecordRedirects() RedirectPolicy {
+	return func(req *Request, via []*Request) error {
+		rc, _ := req.Context().Value(redirectChainKey{}).(*redirectChain)
+		if rc == nil {
+			return nil
+		}
+		hops := make([]RedirectHop, 0, len(via)+1)
+		for _, r := range via {
+			hops = append(hops, newRedirectHop(r))
+		}
+		hops = append(hops, newRedirectHop(req))
+		rc.mu.Lock()
+		rc.hops = hops
+		rc.mu.Unlock()
+		return nil
+	}
+}
+
+func newRedirectHop(r *Request) RedirectHop {
+	h := RedirectHop{Method: r.Method, URL: r.URL}
+	if h.Method == "" {
+		h.Method = MethodGet
+	}
+	if r.Response != nil {
+		h.StatusCode = r.Response.StatusCode
+	}
+	return h
+}
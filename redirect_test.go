@@ -0,0 +1,142 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type redirectRoundTripper map[string]string
+
+// RoundTrip answers requests for URLs in the map with a redirect to
+// the mapped location, and all others with 200 OK. It records the
+// Authorization header of the final request in the response body.
+func (m redirectRoundTripper) RoundTrip(req *Request) (*Response, error) {
+	res := &Response{
+		StatusCode: StatusOK,
+		Header:     make(Header),
+		Body:       io.NopCloser(strings.NewReader(req.Header.Get("Authorization"))),
+		Request:    req,
+	}
+	if loc, ok := m[req.URL.String()]; ok {
+		res.StatusCode = StatusFound
+		res.Header.Set("Location", loc)
+		res.Body = NoBody
+	}
+	return res, nil
+}
+
+func TestRedirectPolicies(t *testing.T) {
+	rt := redirectRoundTripper{
+		"http://a.example/1":     "http://a.example/2",
+		"http://a.example/2":     "http://a.example/3",
+		"http://a.example/cross": "http://b.example/",
+		"http://a.example/port":  "http://a.example:8080/",
+		"http://a.example/up":    "https://a.example/",
+		"https://a.example/down": "http://a.example/",
+		"https://a.example/sub":  "https://sub.a.example/",
+	}
+	tests := []struct {
+		policy  RedirectPolicy
+		url     string
+		blocked bool
+	}{
+		{MaxRedirects(2), "http://a.example/1", false},
+		{MaxRedirects(1), "http://a.example/1", true},
+		{SameHostRedirects(), "http://a.example/1", false},
+		{SameHostRedirects(), "http://a.example/port", false},
+		{SameHostRedirects(), "http://a.example/cross", true},
+		{SameSchemeRedirects(), "http://a.example/up", true},
+		{SameSchemeRedirects(), "http://a.example/cross", false},
+		{NoDowngradeRedirects(), "http://a.example/up", false},
+		{NoDowngradeRedirects(), "https://a.example/down", true},
+		{ComposeRedirectPolicies(MaxRedirects(5), SameHostRedirects()), "http://a.example/cross", true},
+	}
+	for _, tt := range tests {
+		c := &Client{Transport: rt, CheckRedirect: tt.policy}
+		res, err := c.Get(tt.url)
+		if err == nil {
+			res.Body.Close()
+		}
+		if tt.blocked && !errors.Is(err, ErrRedirectBlocked) {
+			t.Errorf("Get(%q) error = %v; want ErrRedirectBlocked", tt.url, err)
+		}
+		if !tt.blocked && err != nil {
+			t.Errorf("Get(%q) = %v; want success", tt.url, err)
+		}
+	}
+
+	c := &Client{Transport: rt, CheckRedirect: SameHostRedirects()}
+	if _, err := c.Get("http://a.example/cross"); !errors.Is(err, ErrRedirectBlocked) {
+		t.Errorf("cross-host Get error = %v; want ErrRedirectBlocked", err)
+	}
+}
+
+func TestStripSensitiveHeaders(t *testing.T) {
+	rt := redirectRoundTripper{
+		"http://a.example/same":  "http://a.example/other",
+		"http://a.example/port":  "http://a.example:8080/",
+		"https://a.example/sub":  "https://sub.a.example/",
+		"http://a.example/cross": "http://b.example/",
+	}
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"http://a.example/same", "secret"},
+		{"http://a.example/port", ""},
+		{"https://a.example/sub", ""},
+		{"http://a.example/cross", ""},
+	}
+	c := &Client{Transport: rt, CheckRedirect: StripSensitiveHeaders()}
+	for _, tt := range tests {
+		req, _ := NewRequest("GET", tt.url, nil)
+		req.Header.Set("Authorization", "secret")
+		res, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if got := string(b); got != tt.want {
+			t.Errorf("%s: Authorization after redirect = %q; want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestRecordRedirects(t *testing.T) {
+	rt := redirectRoundTripper{
+		"http://a.example/1": "http://a.example/2",
+		"http://a.example/2": "http://b.example/3",
+	}
+	c := &Client{
+		Transport:     rt,
+		CheckRedirect: ComposeRedirectPolicies(RecordRedirects(), SameHostRedirects()),
+	}
+	req, _ := NewRequestWithContext(WithRedirectChain(context.Background()), "GET", "http://a.example/1", nil)
+	if _, err := c.Do(req); err == nil {
+		t.Fatal("Do succeeded; want cross-host redirect refused")
+	}
+	var got []string
+	for _, h := range RedirectChainFromContext(req.Context()) {
+		got = append(got, h.String())
+	}
+	want := []string{
+		"GET http://a.example/1",
+		"302 -> GET http://a.example/2",
+		"302 -> GET http://b.example/3",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("chain = %q; want %q", got, want)
+	}
+
+	if chain := RedirectChainFromContext(context.Background()); chain != nil {
+		t.Errorf("chain without WithRedirectChain = %v; want nil", chain)
+	}
+}
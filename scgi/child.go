@@ -0,0 +1,184 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scgi
+
+// This file implements SCGI from the perspective of the application.
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/johnsiilver/http/cgi"
+)
+
+// envVarsContextKey uniquely identifies a mapping of SCGI request
+// headers to their values in a request context.
+type envVarsContextKey struct{}
+
+// response implements http.ResponseWriter.
+type response struct {
+	conn           net.Conn
+	header         http.Header
+	code           int
+	wroteHeader    bool
+	wroteCGIHeader bool
+	w              *bufio.Writer
+}
+
+func newResponse(conn net.Conn) *response {
+	return &response{
+		conn:   conn,
+		header: http.Header{},
+		w:      bufio.NewWriter(conn),
+	}
+}
+
+func (r *response) Header() http.Header {
+	return r.header
+}
+
+func (r *response) Write(p []byte) (n int, err error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if !r.wroteCGIHeader {
+		r.writeCGIHeader(p)
+	}
+	return r.w.Write(p)
+}
+
+func (r *response) WriteHeader(code int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	r.code = code
+	if code == http.StatusNotModified {
+		// Must not have body.
+		r.header.Del("Content-Type")
+		r.header.Del("Content-Length")
+		r.header.Del("Transfer-Encoding")
+	}
+	if r.header.Get("Date") == "" {
+		r.header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+}
+
+// writeCGIHeader finalizes the header sent to the client and writes it to the output.
+// p is not written by writeHeader, but is the first chunk of the body
+// that will be written. It is sniffed for a Content-Type if none is
+// set explicitly.
+func (r *response) writeCGIHeader(p []byte) {
+	if r.wroteCGIHeader {
+		return
+	}
+	r.wroteCGIHeader = true
+	fmt.Fprintf(r.w, "Status: %d %s\r\n", r.code, http.StatusText(r.code))
+	if _, hasType := r.header["Content-Type"]; r.code != http.StatusNotModified && !hasType {
+		r.header.Set("Content-Type", http.DetectContentType(p))
+	}
+	r.header.Write(r.w)
+	r.w.WriteString("\r\n")
+}
+
+func (r *response) Flush() {
+	r.FlushError()
+}
+
+// FlushError writes the header, if it has not been written, and any
+// buffered data to the web server.
+func (r *response) FlushError() error {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.writeCGIHeader(nil)
+	return r.w.Flush()
+}
+
+func (r *response) SetReadDeadline(t time.Time) error {
+	return r.conn.SetReadDeadline(t)
+}
+
+func (r *response) SetWriteDeadline(t time.Time) error {
+	return r.conn.SetWriteDeadline(t)
+}
+
+// serveConn serves the single request on conn.
+func serveConn(conn net.Conn, handler http.Handler) {
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	params, err := readHeaders(br)
+	if err != nil {
+		// There is no request to reply to.
+		return
+	}
+	r := newResponse(conn)
+	req, err := cgi.RequestFromMap(params)
+	if err != nil {
+		r.WriteHeader(http.StatusInternalServerError)
+	} else {
+		body := io.NopCloser(io.LimitReader(br, max(req.ContentLength, 0)))
+		if req.ContentLength > 0 {
+			req.Body = body
+		} else {
+			req.Body = http.NoBody
+		}
+		ctx, cancel := context.WithCancel(context.WithValue(req.Context(), envVarsContextKey{}, params))
+		defer cancel()
+		handler.ServeHTTP(r, req.WithContext(ctx))
+
+		// Consume the rest of the body, so the web server isn't still
+		// writing to us when we close the connection, which would
+		// send a RST. For now just bound it a little.
+		defer io.CopyN(io.Discard, body, 100<<20)
+	}
+	// Make sure we serve something even if nothing was written to r
+	r.Write(nil)
+	r.FlushError()
+}
+
+// Serve accepts incoming SCGI connections on the listener l, creating a
+// new goroutine for each. The goroutine reads the request and then
+// calls handler to reply to it.
+// If l is nil, Serve accepts connections from os.Stdin.
+// If handler is nil, [http.DefaultServeMux] is used.
+//
+// The response writer supports [http.ResponseController]: flushing
+// sends the header and any buffered body to the web server, and
+// deadlines apply to the connection to the web server.
+func Serve(l net.Listener, handler http.Handler) error {
+	if l == nil {
+		var err error
+		l, err = net.FileListener(os.Stdin)
+		if err != nil {
+			return err
+		}
+		defer l.Close()
+	}
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+	for {
+		rw, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go serveConn(rw, handler)
+	}
+}
+
+// ProcessEnv returns the SCGI request headers of the request r, which
+// include the variables that are not reflected in r itself, such as
+// REMOTE_USER.
+func ProcessEnv(r *http.Request) map[string]string {
+	env, _ := r.Context().Value(envVarsContextKey{}).(map[string]string)
+	return env
+}
@@ -0,0 +1,193 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scgi
+
+// This file implements the web server side of SCGI, forwarding
+// requests to an application.
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/johnsiilver/http/httputil"
+	"github.com/johnsiilver/http/internal/cgienv"
+)
+
+// Handler forwards requests to an SCGI application, such as one
+// running [Serve] or behind uWSGI.
+//
+// SCGI requires the length of the request body, so requests whose
+// body has no declared length, such as chunked HTTP/1.1 uploads, are
+// rejected with 411 (Length Required).
+type Handler struct {
+	Network string // network of Addr, "tcp" or "unix"; "tcp" if empty
+	Addr    string // address of the application
+	Root    string // root URI prefix of handler or empty for "/"
+
+	Env    []string    // extra request headers to send, if any, as "key=value"
+	Logger *log.Logger // optional log for errors or nil to use log.Print
+
+	// Dial optionally specifies the dial function for connecting
+	// to the application. If nil, a zero net.Dialer is used.
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.ContentLength < 0 {
+		rw.WriteHeader(http.StatusLengthRequired)
+		return
+	}
+
+	env := cgienv.Request(req, h.Root, "")
+	env = append(env, h.Env...)
+	env = cgienv.RemoveLeadingDuplicates(env)
+	// CONTENT_LENGTH must come first, even if it is zero, and SCGI
+	// must be set.
+	headers := []string{"CONTENT_LENGTH=" + strconv.FormatInt(req.ContentLength, 10), "SCGI=1"}
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, "CONTENT_LENGTH=") && !strings.HasPrefix(kv, "SCGI=") {
+			headers = append(headers, kv)
+		}
+	}
+
+	ctx := req.Context()
+	conn, err := h.dial(ctx)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadGateway)
+		h.printf("scgi: %v", err)
+		return
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	bw := bufio.NewWriter(conn)
+	err = writeHeaders(bw, headers)
+	if err == nil && req.ContentLength > 0 {
+		_, err = io.Copy(bw, req.Body)
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		rw.WriteHeader(http.StatusBadGateway)
+		h.printf("scgi: error sending request: %v", err)
+		return
+	}
+
+	br := bufio.NewReader(conn)
+	statusCode, header, err := readResponseHeader(br)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadGateway)
+		h.printf("scgi: error reading response: %v", err)
+		return
+	}
+
+	// As for cgi.Handler, an application sending trailers uses
+	// chunked encoding: decode the body and read the trailer that
+	// follows.
+	var body io.Reader = br
+	chunked := header.Get("Transfer-Encoding") == "chunked"
+	if chunked {
+		header.Del("Content-Length")
+		body = httputil.NewChunkedReader(br)
+	}
+	for _, k := range []string{"Connection", "Keep-Alive", "Transfer-Encoding"} {
+		header.Del(k)
+	}
+	for k, vv := range header {
+		rw.Header()[k] = append(rw.Header()[k], vv...)
+	}
+	rw.WriteHeader(statusCode)
+
+	err = cgienv.CopyFlush(rw, body)
+	if err == nil && chunked {
+		var trailer textproto.MIMEHeader
+		trailer, err = textproto.NewReader(br).ReadMIMEHeader()
+		for k, vv := range trailer {
+			rw.Header()[http.TrailerPrefix+k] = vv
+		}
+	}
+	if err != nil {
+		h.printf("scgi: copy error: %v", err)
+	}
+}
+
+func (h *Handler) dial(ctx context.Context) (net.Conn, error) {
+	network := h.Network
+	if network == "" {
+		network = "tcp"
+	}
+	if h.Dial != nil {
+		return h.Dial(ctx, network, h.Addr)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, network, h.Addr)
+}
+
+// readResponseHeader reads the header of a CGI response, returning its
+// status, from the Status header or from an HTTP status line, which
+// some applications send instead.
+func readResponseHeader(br *bufio.Reader) (int, http.Header, error) {
+	tp := textproto.NewReader(br)
+	statusCode := 0
+	if b, _ := br.Peek(5); string(b) == "HTTP/" {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return 0, nil, err
+		}
+		_, status, _ := strings.Cut(line, " ")
+		if statusCode, err = parseStatus(status); err != nil {
+			return 0, nil, err
+		}
+	}
+	mh, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return 0, nil, err
+	}
+	header := http.Header(mh)
+	if status := header.Get("Status"); status != "" {
+		if statusCode, err = parseStatus(status); err != nil {
+			return 0, nil, err
+		}
+		header.Del("Status")
+	}
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+		if header.Get("Location") != "" {
+			statusCode = http.StatusFound
+		}
+	}
+	return statusCode, header, nil
+}
+
+// parseStatus parses the code at the start of a status such as
+// "404 Not Found".
+func parseStatus(status string) (int, error) {
+	if len(status) < 3 {
+		return 0, errors.New("bogus status (short): " + strconv.Quote(status))
+	}
+	code, err := strconv.Atoi(status[:3])
+	if err != nil || code < 100 {
+		return 0, errors.New("bogus status: " + strconv.Quote(status))
+	}
+	return code, nil
+}
+
+func (h *Handler) printf(format string, v ...any) {
+	if h.Logger != nil {
+		h.Logger.Printf(format, v...)
+	} else {
+		log.Printf(format, v...)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package scgi implements the Simple Common Gateway Interface (SCGI)
+// protocol, both for applications, with [Serve], and for web servers
+// forwarding requests to them, with [Handler].
+//
+// See https://python.ca/scgi/protocol.txt for the specification.
+//
+// An SCGI request is a netstring of NUL-separated header names and
+// values, the same meta-variables as for CGI, followed by the request
+// body. The application replies with a CGI response and closes the
+// connection.
+package scgi
+
+// This file defines the encoding of request headers used by the
+// application and the web server.
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxHeaderSize limits the size of the headers of a request read by
+// Serve.
+const maxHeaderSize = 1 << 20
+
+var errMalformed = errors.New("scgi: malformed request headers")
+
+// readHeaders reads the netstring of headers beginning a request.
+func readHeaders(r *bufio.Reader) (map[string]string, error) {
+	n := 0
+	for i := 0; ; i++ {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if c == ':' && i > 0 {
+			break
+		}
+		if c < '0' || c > '9' || i == 8 {
+			return nil, errMalformed
+		}
+		n = n*10 + int(c-'0')
+	}
+	if n > maxHeaderSize {
+		return nil, errors.New("scgi: request headers too large")
+	}
+	buf := make([]byte, n+1)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	if buf[n] != ',' {
+		return nil, errMalformed
+	}
+	// Each name and value ends with a NUL, so splitting leaves an
+	// empty field at the end.
+	fields := bytes.Split(buf[:n], []byte{0})
+	if len(fields)%2 != 1 || len(fields[len(fields)-1]) != 0 {
+		return nil, errMalformed
+	}
+	fields = fields[:len(fields)-1]
+	if len(fields) == 0 || string(fields[0]) != "CONTENT_LENGTH" {
+		return nil, errors.New("scgi: first request header is not CONTENT_LENGTH")
+	}
+	params := make(map[string]string, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		params[string(fields[i])] = string(fields[i+1])
+	}
+	if params["SCGI"] != "1" {
+		return nil, errors.New("scgi: missing SCGI request header")
+	}
+	return params, nil
+}
+
+// writeHeaders writes env, "key=value" strings beginning with
+// CONTENT_LENGTH, as the netstring of headers beginning a request.
+func writeHeaders(w io.Writer, env []string) error {
+	var b bytes.Buffer
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+		b.WriteString(k)
+		b.WriteByte(0)
+		b.WriteString(v)
+		b.WriteByte(0)
+	}
+	_, err := fmt.Fprintf(w, "%d:%s,", b.Len(), b.Bytes())
+	return err
+}
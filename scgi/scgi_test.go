@@ -0,0 +1,275 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scgi
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/johnsiilver/http/httptest"
+)
+
+func TestHeaders(t *testing.T) {
+	var b bytes.Buffer
+	env := []string{"CONTENT_LENGTH=27", "SCGI=1", "REQUEST_METHOD=POST", "REQUEST_URI=/deepthought", "EMPTY="}
+	if err := writeHeaders(&b, env); err != nil {
+		t.Fatal(err)
+	}
+	want := "77:CONTENT_LENGTH\x0027\x00SCGI\x001\x00REQUEST_METHOD\x00POST\x00REQUEST_URI\x00/deepthought\x00EMPTY\x00\x00,"
+	if got := b.String(); got != want {
+		t.Fatalf("writeHeaders:\n%q\nwant:\n%q", got, want)
+	}
+	got, err := readHeaders(bufio.NewReader(&b))
+	if err != nil {
+		t.Fatalf("readHeaders: %v", err)
+	}
+	wantMap := map[string]string{
+		"CONTENT_LENGTH": "27",
+		"SCGI":           "1",
+		"REQUEST_METHOD": "POST",
+		"REQUEST_URI":    "/deepthought",
+		"EMPTY":          "",
+	}
+	if !maps.Equal(got, wantMap) {
+		t.Errorf("readHeaders = %v; want %v", got, wantMap)
+	}
+}
+
+func TestReadHeadersMalformed(t *testing.T) {
+	for _, in := range []string{
+		"",
+		":,",
+		"x:,",
+		"0:,",
+		"123456789:",
+		"9:SCGI\x001\x00",
+		"7:SCGI\x001\x00;",
+		"6:SCGI\x001,",
+		"7:SCGI\x001\x00,",
+		"24:CONTENT_LENGTH\x000\x00SCGI\x000\x00,",
+		"17:CONTENT_LENGTH\x000\x00,",
+	} {
+		if params, err := readHeaders(bufio.NewReader(strings.NewReader(in))); err == nil {
+			t.Errorf("readHeaders(%q) = %v; want error", in, params)
+		}
+	}
+}
+
+// serveApp runs Serve with handler on a new listener and returns a
+// Handler forwarding to it.
+func serveApp(t *testing.T, handler http.Handler) *Handler {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go Serve(l, handler)
+	return &Handler{Addr: l.Addr().String(), Root: "/app"}
+}
+
+func TestServe(t *testing.T) {
+	h := serveApp(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Test", "yes")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "method=%s\n", r.Method)
+		fmt.Fprintf(w, "path=%s\n", r.URL.Path)
+		fmt.Fprintf(w, "query=%s\n", r.URL.RawQuery)
+		fmt.Fprintf(w, "host=%s\n", r.Host)
+		fmt.Fprintf(w, "body=%s\n", body)
+		fmt.Fprintf(w, "script=%s\n", ProcessEnv(r)["SCRIPT_NAME"])
+		fmt.Fprintf(w, "path-info=%s\n", ProcessEnv(r)["PATH_INFO"])
+		fmt.Fprintf(w, "remote-user=%s\n", ProcessEnv(r)["REMOTE_USER"])
+	}))
+	h.Env = []string{"REMOTE_USER=gopher"}
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	res, err := ts.Client().Post(ts.URL+"/app/deep/thought?q=42", "text/plain", strings.NewReader("life"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("status = %d; want 201", res.StatusCode)
+	}
+	if got := res.Header.Get("X-Test"); got != "yes" {
+		t.Errorf("X-Test = %q; want yes", got)
+	}
+	want := strings.Join([]string{
+		"method=POST",
+		"path=/app/deep/thought",
+		"query=q=42",
+		"host=" + strings.TrimPrefix(ts.URL, "http://"),
+		"body=life",
+		"script=/app",
+		"path-info=/deep/thought",
+		"remote-user=gopher",
+		"",
+	}, "\n")
+	if string(b) != want {
+		t.Errorf("body:\n%s\nwant:\n%s", b, want)
+	}
+}
+
+func TestServeFlush(t *testing.T) {
+	release := make(chan bool)
+	h := serveApp(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "first\n")
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush: %v", err)
+		}
+		<-release
+		io.WriteString(w, "second\n")
+	}))
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	res, err := ts.Client().Get(ts.URL + "/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	br := bufio.NewReader(res.Body)
+	// The first line arrives before the handler is released.
+	if line, err := br.ReadString('\n'); line != "first\n" || err != nil {
+		t.Fatalf("first line = %q, %v", line, err)
+	}
+	close(release)
+	if rest, err := io.ReadAll(br); string(rest) != "second\n" || err != nil {
+		t.Errorf("rest = %q, %v", rest, err)
+	}
+}
+
+// fakeApp accepts one connection, reads the request headers and
+// replies with resp.
+func fakeApp(t *testing.T, resp string) *Handler {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		if _, err := readHeaders(bufio.NewReader(c)); err != nil {
+			t.Errorf("application: %v", err)
+			return
+		}
+		io.WriteString(c, resp)
+	}()
+	return &Handler{Addr: l.Addr().String()}
+}
+
+func TestHandlerResponses(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		resp        string
+		wantCode    int
+		wantBody    string
+		wantTrailer string
+	}{
+		{
+			name:     "status header",
+			resp:     "Status: 404 Not Found\r\nContent-Type: text/plain\r\n\r\nnope",
+			wantCode: http.StatusNotFound,
+			wantBody: "nope",
+		},
+		{
+			name:     "status line",
+			resp:     "HTTP/1.1 202 Accepted\r\nContent-Type: text/plain\r\nConnection: close\r\n\r\nok",
+			wantCode: http.StatusAccepted,
+			wantBody: "ok",
+		},
+		{
+			name:     "no status",
+			resp:     "Content-Type: text/plain\n\nhi",
+			wantCode: http.StatusOK,
+			wantBody: "hi",
+		},
+		{
+			name:     "redirect",
+			resp:     "Location: http://example.com/\r\n\r\n",
+			wantCode: http.StatusFound,
+		},
+		{
+			name:        "trailers",
+			resp:        "Status: 200 OK\r\nTrailer: X-Sum\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\nX-Sum: 5\r\n\r\n",
+			wantCode:    http.StatusOK,
+			wantBody:    "hello",
+			wantTrailer: "5",
+		},
+		{
+			name:     "bogus status",
+			resp:     "Status: abc\r\n\r\n",
+			wantCode: http.StatusBadGateway,
+		},
+		{
+			name:     "no response",
+			wantCode: http.StatusBadGateway,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := fakeApp(t, tt.resp)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+			res := rec.Result()
+			if res.StatusCode != tt.wantCode {
+				t.Errorf("status = %d; want %d", res.StatusCode, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusBadGateway && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q; want %q", rec.Body, tt.wantBody)
+			}
+			if got := res.Trailer.Get("X-Sum"); got != tt.wantTrailer {
+				t.Errorf("trailer X-Sum = %q; want %q", got, tt.wantTrailer)
+			}
+			for _, k := range []string{"Connection", "Transfer-Encoding", "Status"} {
+				if v, ok := res.Header[k]; ok {
+					t.Errorf("header %s: %q was copied from the SCGI response", k, v)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	t.Run("unreachable", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := l.Addr().String()
+		l.Close()
+		rec := httptest.NewRecorder()
+		(&Handler{Addr: addr}).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if rec.Code != http.StatusBadGateway {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusBadGateway)
+		}
+	})
+
+	t.Run("unknown length", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/", strings.NewReader("body"))
+		req.ContentLength = -1
+		rec := httptest.NewRecorder()
+		(&Handler{Addr: "127.0.0.1:1"}).ServeHTTP(rec, req)
+		if rec.Code != http.StatusLengthRequired {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusLengthRequired)
+		}
+	})
+}
@@ -0,0 +1,30 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !plan9
+
+package socks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+)
+
+// replyForError maps a dial error to the closest reply code.
+func replyForError(err error) Reply {
+	var dnsErr *net.DNSError
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return StatusConnectionRefused
+	case errors.Is(err, syscall.ENETUNREACH):
+		return StatusNetworkUnreachable
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.As(err, &dnsErr):
+		return StatusHostUnreachable
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, syscall.ETIMEDOUT):
+		return StatusTTLExpired
+	}
+	return StatusGeneralFailure
+}
@@ -0,0 +1,25 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package socks
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// replyForError maps a dial error to the closest reply code.
+// Plan 9 has no errno values, so only resolver errors and
+// timeouts are distinguished.
+func replyForError(err error) Reply {
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &dnsErr):
+		return StatusHostUnreachable
+	case errors.Is(err, context.DeadlineExceeded):
+		return StatusTTLExpired
+	}
+	return StatusGeneralFailure
+}
@@ -0,0 +1,546 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package socks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrServerClosed is returned by the [Server.Serve] and
+// [Server.ListenAndServe] methods after a call to [Server.Close].
+var ErrServerClosed = errors.New("socks: Server closed")
+
+// A Server is a SOCKS version 5 proxy server.
+//
+// It supports the CONNECT command and, optionally, the UDP ASSOCIATE
+// command. BIND is not supported.
+//
+// The zero value for Server is a valid configuration that accepts
+// unauthenticated CONNECT requests for any destination.
+type Server struct {
+	// Authenticate, if non-nil, requires clients to use the
+	// username/password authentication method defined in RFC 1929.
+	// It reports whether the supplied credentials are acceptable.
+	// If nil, only AuthMethodNotRequired is accepted.
+	Authenticate func(username, password string) bool
+
+	// Dial specifies the optional dial function used to connect to
+	// the target of a CONNECT command. If nil, a net.Dialer is used.
+	// The context passed to Dial is canceled by [Server.Close].
+	Dial func(ctx context.Context, network, address string) (net.Conn, error)
+
+	// EnableUDPAssociate permits the UDP ASSOCIATE command. If false,
+	// such requests are answered with StatusCommandNotSupported.
+	EnableUDPAssociate bool
+
+	// HandshakeTimeout, if non-zero, bounds the time a client has to
+	// complete method negotiation, authentication and the command
+	// request.
+	HandshakeTimeout time.Duration
+
+	// ErrorLog specifies an optional logger for errors accepting
+	// connections and unexpected behavior from clients.
+	// If nil, logging is done via the log package's standard logger.
+	ErrorLog *log.Logger
+
+	mu        sync.Mutex // guards closed, ctx, listeners and conns
+	closed    bool
+	ctx       context.Context // canceled by Close; created on first use
+	cancel    context.CancelFunc
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup // counts active connections
+}
+
+// ListenAndServe listens on the network address and then calls
+// [Server.Serve] to handle incoming connections.
+//
+// ListenAndServe always returns a non-nil error.
+func (s *Server) ListenAndServe(network, address string) error {
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts incoming connections on the listener l, creating a
+// new service goroutine for each.
+//
+// Serve always returns a non-nil error and closes l.
+// After [Server.Close], the returned error is [ErrServerClosed].
+func (s *Server) Serve(l net.Listener) error {
+	if !s.trackListener(l, true) {
+		l.Close()
+		return ErrServerClosed
+	}
+	defer s.trackListener(l, false)
+	defer l.Close()
+
+	var tempDelay time.Duration // how long to sleep on accept failure
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				if tempDelay == 0 {
+					tempDelay = 5 * time.Millisecond
+				} else {
+					tempDelay *= 2
+				}
+				if max := 1 * time.Second; tempDelay > max {
+					tempDelay = max
+				}
+				s.logf("socks: Accept error: %v; retrying in %v", err, tempDelay)
+				time.Sleep(tempDelay)
+				continue
+			}
+			return err
+		}
+		tempDelay = 0
+		if !s.trackConn(c, true) {
+			c.Close()
+			return ErrServerClosed
+		}
+		go func() {
+			defer s.trackConn(c, false)
+			s.serveConn(c)
+		}()
+	}
+}
+
+// Close immediately closes all listeners and active connections,
+// and waits for the connection goroutines to exit.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	if s.cancel != nil {
+		s.cancel()
+	}
+	var err error
+	for l := range s.listeners {
+		if cerr := l.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// baseContext returns the context for the outgoing connections of s,
+// which is canceled by Close.
+func (s *Server) baseContext() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx == nil {
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+		if s.closed {
+			s.cancel()
+		}
+	}
+	return s.ctx
+}
+
+// trackListener adds or removes l from the set of tracked listeners.
+// It reports false if l was to be added and the server is closed.
+func (s *Server) trackListener(l net.Listener, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if add {
+		if s.closed {
+			return false
+		}
+		if s.listeners == nil {
+			s.listeners = make(map[net.Listener]struct{})
+		}
+		s.listeners[l] = struct{}{}
+	} else {
+		delete(s.listeners, l)
+	}
+	return true
+}
+
+// trackConn adds or removes c from the set of tracked connections.
+// It reports false if c was to be added and the server is closed.
+func (s *Server) trackConn(c net.Conn, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if add {
+		if s.closed {
+			return false
+		}
+		if s.conns == nil {
+			s.conns = make(map[net.Conn]struct{})
+		}
+		s.conns[c] = struct{}{}
+		s.wg.Add(1)
+	} else {
+		delete(s.conns, c)
+		s.wg.Done()
+	}
+	return true
+}
+
+func (s *Server) logf(format string, args ...any) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// serveConn runs the SOCKS protocol on a newly accepted connection.
+func (s *Server) serveConn(c net.Conn) {
+	defer c.Close()
+
+	if s.HandshakeTimeout > 0 {
+		c.SetDeadline(time.Now().Add(s.HandshakeTimeout))
+	}
+	if err := s.negotiate(c); err != nil {
+		s.logf("socks: negotiation with %v failed: %v", c.RemoteAddr(), err)
+		return
+	}
+	cmd, dst, err := readRequest(c)
+	if err != nil {
+		var rerr replyError
+		if errors.As(err, &rerr) {
+			writeReply(c, rerr.code, nil)
+		}
+		s.logf("socks: bad request from %v: %v", c.RemoteAddr(), err)
+		return
+	}
+	if s.HandshakeTimeout > 0 {
+		c.SetDeadline(noDeadline)
+	}
+
+	switch cmd {
+	case CmdConnect:
+		s.handleConnect(c, dst)
+	case CmdUDPAssociate:
+		if !s.EnableUDPAssociate {
+			writeReply(c, StatusCommandNotSupported, nil)
+			return
+		}
+		s.handleUDPAssociate(c, dst)
+	default:
+		writeReply(c, StatusCommandNotSupported, nil)
+	}
+}
+
+// negotiate performs method selection and, if required,
+// username/password authentication.
+func (s *Server) negotiate(c net.Conn) error {
+	var b [2 + 255]byte
+	if _, err := io.ReadFull(c, b[:2]); err != nil {
+		return err
+	}
+	if b[0] != Version5 {
+		return errors.New("unexpected protocol version " + strconv.Itoa(int(b[0])))
+	}
+	methods := b[2 : 2+int(b[1])]
+	if _, err := io.ReadFull(c, methods); err != nil {
+		return err
+	}
+
+	want := AuthMethodNotRequired
+	if s.Authenticate != nil {
+		want = AuthMethodUsernamePassword
+	}
+	if bytes.IndexByte(methods, byte(want)) < 0 {
+		c.Write([]byte{Version5, byte(AuthMethodNoAcceptableMethods)})
+		return errors.New("no acceptable authentication methods")
+	}
+	if _, err := c.Write([]byte{Version5, byte(want)}); err != nil {
+		return err
+	}
+	if want != AuthMethodUsernamePassword {
+		return nil
+	}
+
+	username, password, err := readUsernamePassword(c)
+	if err != nil {
+		return err
+	}
+	if !s.Authenticate(username, password) {
+		c.Write([]byte{authUsernamePasswordVersion, authStatusFailed})
+		return errors.New("username/password authentication failed")
+	}
+	_, err = c.Write([]byte{authUsernamePasswordVersion, authStatusSucceeded})
+	return err
+}
+
+// readUsernamePassword reads an RFC 1929 username/password request.
+func readUsernamePassword(r io.Reader) (username, password string, err error) {
+	var b [255]byte
+	if _, err := io.ReadFull(r, b[:2]); err != nil {
+		return "", "", err
+	}
+	if b[0] != authUsernamePasswordVersion {
+		return "", "", errors.New("invalid username/password version")
+	}
+	ulen := int(b[1])
+	if _, err := io.ReadFull(r, b[:ulen]); err != nil {
+		return "", "", err
+	}
+	username = string(b[:ulen])
+	if _, err := io.ReadFull(r, b[:1]); err != nil {
+		return "", "", err
+	}
+	plen := int(b[0])
+	if _, err := io.ReadFull(r, b[:plen]); err != nil {
+		return "", "", err
+	}
+	password = string(b[:plen])
+	return username, password, nil
+}
+
+// A replyError is a request error that should be reported to the
+// client with the given reply code.
+type replyError struct {
+	code Reply
+	err  error
+}
+
+func (e replyError) Error() string { return e.err.Error() }
+
+// readRequest reads a command request and returns the command and
+// its destination address.
+func readRequest(r io.Reader) (Command, *Addr, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, nil, err
+	}
+	if b[0] != Version5 {
+		return 0, nil, errors.New("unexpected protocol version " + strconv.Itoa(int(b[0])))
+	}
+	if b[2] != 0 {
+		return 0, nil, errors.New("non-zero reserved field")
+	}
+	a, err := readAddr(r, b[3])
+	if err != nil {
+		return 0, nil, err
+	}
+	return Command(b[1]), a, nil
+}
+
+// readAddr reads an address of the given type followed by a port.
+func readAddr(r io.Reader, typ byte) (*Addr, error) {
+	var b [255]byte
+	a := new(Addr)
+	switch typ {
+	case AddrTypeIPv4:
+		a.IP = make(net.IP, net.IPv4len)
+		if _, err := io.ReadFull(r, a.IP); err != nil {
+			return nil, err
+		}
+	case AddrTypeIPv6:
+		a.IP = make(net.IP, net.IPv6len)
+		if _, err := io.ReadFull(r, a.IP); err != nil {
+			return nil, err
+		}
+	case AddrTypeFQDN:
+		if _, err := io.ReadFull(r, b[:1]); err != nil {
+			return nil, err
+		}
+		n := int(b[0])
+		if _, err := io.ReadFull(r, b[:n]); err != nil {
+			return nil, err
+		}
+		a.Name = string(b[:n])
+	default:
+		return nil, replyError{StatusAddrTypeNotSupported, errors.New("unknown address type " + strconv.Itoa(int(typ)))}
+	}
+	if _, err := io.ReadFull(r, b[:2]); err != nil {
+		return nil, err
+	}
+	a.Port = int(b[0])<<8 | int(b[1])
+	return a, nil
+}
+
+// appendAddr appends the wire encoding of a, including its port, to b.
+// A nil a is encoded as the IPv4 unspecified address.
+func appendAddr(b []byte, a *Addr) []byte {
+	if a == nil {
+		return append(b, AddrTypeIPv4, 0, 0, 0, 0, 0, 0)
+	}
+	if ip4 := a.IP.To4(); ip4 != nil {
+		b = append(b, AddrTypeIPv4)
+		b = append(b, ip4...)
+	} else if a.IP != nil {
+		b = append(b, AddrTypeIPv6)
+		b = append(b, a.IP.To16()...)
+	} else {
+		b = append(b, AddrTypeFQDN, byte(len(a.Name)))
+		b = append(b, a.Name...)
+	}
+	return append(b, byte(a.Port>>8), byte(a.Port))
+}
+
+// addrFromNet converts a TCP or UDP address to an Addr.
+// It returns nil for other address types.
+func addrFromNet(na net.Addr) *Addr {
+	switch na := na.(type) {
+	case *net.TCPAddr:
+		return &Addr{IP: na.IP, Port: na.Port}
+	case *net.UDPAddr:
+		return &Addr{IP: na.IP, Port: na.Port}
+	}
+	return nil
+}
+
+func writeReply(w io.Writer, code Reply, bound *Addr) error {
+	b := []byte{Version5, byte(code), 0}
+	b = appendAddr(b, bound)
+	_, err := w.Write(b)
+	return err
+}
+
+func (s *Server) handleConnect(c net.Conn, dst *Addr) {
+	dial := s.Dial
+	if dial == nil {
+		var d net.Dialer
+		dial = d.DialContext
+	}
+	target, err := dial(s.baseContext(), "tcp", dst.String())
+	if err != nil {
+		writeReply(c, replyForError(err), nil)
+		return
+	}
+	defer target.Close()
+	if err := writeReply(c, StatusSucceeded, addrFromNet(target.LocalAddr())); err != nil {
+		return
+	}
+	relay(c, target)
+}
+
+// relay copies data in both directions between a and b until both
+// directions are finished. A clean EOF in one direction is propagated
+// as a half-close; any other error tears down both connections.
+func relay(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	cp := func(dst, src net.Conn) {
+		_, err := io.Copy(dst, src)
+		cw, ok := dst.(interface{ CloseWrite() error })
+		if err == nil && ok {
+			cw.CloseWrite()
+		} else {
+			dst.Close()
+			src.Close()
+		}
+		done <- struct{}{}
+	}
+	go cp(a, b)
+	go cp(b, a)
+	<-done
+	<-done
+}
+
+// maxUDPDatagram is the largest UDP payload relayed by the server.
+const maxUDPDatagram = 64 << 10
+
+// handleUDPAssociate relays UDP datagrams for the client on c until
+// c is closed, as described in RFC 1928, section 7.
+func (s *Server) handleUDPAssociate(c net.Conn, want *Addr) {
+	host, _, err := net.SplitHostPort(c.LocalAddr().String())
+	if err != nil {
+		writeReply(c, StatusGeneralFailure, nil)
+		return
+	}
+	pc, err := net.ListenPacket("udp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		writeReply(c, StatusGeneralFailure, nil)
+		return
+	}
+	defer pc.Close()
+	if err := writeReply(c, StatusSucceeded, addrFromNet(pc.LocalAddr())); err != nil {
+		return
+	}
+
+	// The association terminates when the control connection does.
+	go func() {
+		io.Copy(io.Discard, c)
+		pc.Close()
+	}()
+
+	var client *net.UDPAddr
+	if ta, ok := c.RemoteAddr().(*net.TCPAddr); ok {
+		client = &net.UDPAddr{IP: ta.IP, Zone: ta.Zone}
+		if want != nil && want.Port != 0 {
+			client.Port = want.Port
+		}
+	}
+	peers := make(map[string]bool) // destinations the client has sent to
+	buf := make([]byte, maxUDPDatagram)
+	for {
+		n, from, err := pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		ua, ok := from.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		if client != nil && ua.IP.Equal(client.IP) && (client.Port == 0 || client.Port == ua.Port) {
+			client.Port = ua.Port
+			dst, payload, err := parseUDPHeader(buf[:n])
+			if err != nil {
+				continue
+			}
+			ra, err := net.ResolveUDPAddr("udp", dst.String())
+			if err != nil {
+				continue
+			}
+			peers[ra.String()] = true
+			pc.WriteTo(payload, ra)
+			continue
+		}
+		if client == nil || client.Port == 0 || !peers[ua.String()] {
+			continue
+		}
+		b := make([]byte, 0, 3+1+net.IPv6len+2+n)
+		b = append(b, 0, 0, 0)
+		b = appendAddr(b, addrFromNet(ua))
+		b = append(b, buf[:n]...)
+		pc.WriteTo(b, client)
+	}
+}
+
+// parseUDPHeader parses the header a client prepends to each UDP
+// datagram. Fragmented datagrams are not supported.
+func parseUDPHeader(b []byte) (*Addr, []byte, error) {
+	if len(b) < 4 {
+		return nil, nil, errors.New("short UDP datagram")
+	}
+	if b[2] != 0 {
+		return nil, nil, fmt.Errorf("unsupported UDP fragment %d", b[2])
+	}
+	r := bytes.NewReader(b[4:])
+	a, err := readAddr(r, b[3])
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, b[len(b)-r.Len():], nil
+}
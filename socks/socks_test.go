@@ -0,0 +1,300 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package socks
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnsiilver/http/httptest"
+)
+
+func newTestServer(t *testing.T, s *Server) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	t.Cleanup(func() { s.Close() })
+	return l.Addr().String()
+}
+
+func newEchoServer(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				io.Copy(c, c)
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func testEcho(t *testing.T, c net.Conn) {
+	t.Helper()
+	const msg = "hello, socks"
+	if _, err := io.WriteString(c, msg); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, len(msg))
+	if _, err := io.ReadFull(c, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != msg {
+		t.Fatalf("echo = %q; want %q", buf, msg)
+	}
+}
+
+func TestServerConnect(t *testing.T) {
+	echo := newEchoServer(t)
+	proxy := newTestServer(t, &Server{})
+
+	d := NewDialer("tcp", proxy)
+	c, err := d.DialContext(context.Background(), "tcp", echo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if ba := c.(*Conn).BoundAddr(); ba == nil || ba.(*Addr).Port == 0 {
+		t.Errorf("BoundAddr = %v; want a non-zero address", ba)
+	}
+	testEcho(t, c)
+}
+
+func TestServerAuthenticate(t *testing.T) {
+	echo := newEchoServer(t)
+	proxy := newTestServer(t, &Server{
+		Authenticate: func(username, password string) bool {
+			return username == "gopher" && password == "secret"
+		},
+	})
+
+	tests := []struct {
+		name    string
+		up      *UsernamePassword
+		wantErr string
+	}{
+		{"ok", &UsernamePassword{"gopher", "secret"}, ""},
+		{"bad password", &UsernamePassword{"gopher", "wrong"}, "authentication failed"},
+		{"no credentials", nil, "no acceptable authentication methods"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDialer("tcp", proxy)
+			if tt.up != nil {
+				d.AuthMethods = []AuthMethod{AuthMethodNotRequired, AuthMethodUsernamePassword}
+				d.Authenticate = tt.up.Authenticate
+			}
+			c, err := d.DialContext(context.Background(), "tcp", echo)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					c.Close()
+					t.Fatalf("DialContext error = %v; want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			testEcho(t, c)
+		})
+	}
+}
+
+func TestServerConnectRefused(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := l.Addr().String()
+	l.Close()
+
+	proxy := newTestServer(t, &Server{})
+	_, err = NewDialer("tcp", proxy).DialContext(context.Background(), "tcp", closed)
+	if err == nil || !strings.Contains(err.Error(), StatusConnectionRefused.String()) {
+		t.Fatalf("DialContext error = %v; want %q", err, StatusConnectionRefused)
+	}
+}
+
+func TestServerUDPAssociate(t *testing.T) {
+	echo, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			echo.WriteTo(buf[:n], from)
+		}
+	}()
+
+	for _, enabled := range []bool{false, true} {
+		proxy := newTestServer(t, &Server{EnableUDPAssociate: enabled})
+		c, err := net.Dial("tcp", proxy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		c.SetDeadline(time.Now().Add(5 * time.Second))
+
+		c.Write([]byte{Version5, 1, byte(AuthMethodNotRequired)})
+		var b [2]byte
+		if _, err := io.ReadFull(c, b[:]); err != nil {
+			t.Fatal(err)
+		}
+		req := appendAddr([]byte{Version5, byte(CmdUDPAssociate), 0}, nil)
+		c.Write(req)
+		var hdr [3]byte
+		if _, err := io.ReadFull(c, hdr[:]); err != nil {
+			t.Fatal(err)
+		}
+		typ := make([]byte, 1)
+		io.ReadFull(c, typ)
+		relay, err := readAddr(c, typ[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !enabled {
+			if got := Reply(hdr[1]); got != StatusCommandNotSupported {
+				t.Fatalf("reply = %v; want %v", got, StatusCommandNotSupported)
+			}
+			continue
+		}
+		if got := Reply(hdr[1]); got != StatusSucceeded {
+			t.Fatalf("reply = %v; want %v", got, StatusSucceeded)
+		}
+
+		uc, err := net.Dial("udp", relay.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer uc.Close()
+		uc.SetDeadline(time.Now().Add(5 * time.Second))
+		dg := appendAddr([]byte{0, 0, 0}, addrFromNet(echo.LocalAddr()))
+		dg = append(dg, "ping"...)
+		if _, err := uc.Write(dg); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 1500)
+		n, err := uc.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		from, payload, err := parseUDPHeader(buf[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if from.String() != echo.LocalAddr().String() || string(payload) != "ping" {
+			t.Fatalf("got %q from %v; want %q from %v", payload, from, "ping", echo.LocalAddr())
+		}
+	}
+}
+
+func TestTransportProxy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "via proxy")
+	}))
+	defer ts.Close()
+	proxy := newTestServer(t, &Server{
+		Authenticate: func(username, password string) bool {
+			return username == "u" && password == "p"
+		},
+	})
+
+	c := &http.Client{Transport: &http.Transport{
+		Proxy: http.ProxyURL(&url.URL{Scheme: "socks5", User: url.UserPassword("u", "p"), Host: proxy}),
+	}}
+	res, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if !bytes.Equal(body, []byte("via proxy")) {
+		t.Fatalf("body = %q; want %q", body, "via proxy")
+	}
+}
+
+func TestServerClose(t *testing.T) {
+	s := &Server{}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- s.Serve(l) }()
+
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	time.Sleep(10 * time.Millisecond)
+	s.Close()
+	if err := <-errc; err != ErrServerClosed {
+		t.Fatalf("Serve = %v; want %v", err, ErrServerClosed)
+	}
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.Read(make([]byte, 1)); err == nil {
+		t.Fatal("Read succeeded after Close; want error")
+	}
+}
+
+func TestServerCloseDial(t *testing.T) {
+	dialing := make(chan struct{})
+	s := &Server{
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			close(dialing)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	proxy := newTestServer(t, s)
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := NewDialer("tcp", proxy).DialContext(context.Background(), "tcp", "192.0.2.1:80")
+		errc <- err
+	}()
+	<-dialing
+	closed := make(chan struct{})
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not cancel a pending dial")
+	}
+	if err := <-errc; err == nil {
+		t.Fatal("DialContext succeeded after Close; want error")
+	}
+}
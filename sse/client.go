@@ -0,0 +1,166 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// DefaultRetry is the default value of [Client.Retry].
+const DefaultRetry = 3 * time.Second
+
+// DefaultMaxRetry is the default value of [Client.MaxRetry].
+const DefaultMaxRetry = time.Minute
+
+// A Client receives events from an event stream, reconnecting when
+// the connection is lost, as a browser's EventSource does.
+//
+// After a connection ends, the Client waits and connects again,
+// sending the ID of the last event received in the Last-Event-ID
+// header. The wait starts at Retry, or at the time set by the
+// server's last retry field, and doubles after each failed attempt,
+// up to MaxRetry.
+//
+// A Client also reconnects after network errors and responses with a
+// 5xx status. It stops on a 204 (No Content) response, which is how a
+// server tells clients to stop, and on any other response that is not
+// a 200 (OK) event stream.
+type Client struct {
+	// Client is the client making requests. If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+
+	URL    string      // URL of the event stream
+	Header http.Header // optional extra headers for each request
+
+	// LastEventID is the ID of the last event received. If it is not
+	// empty when Stream is called, the first request resumes the
+	// stream after it.
+	LastEventID string
+
+	Retry    time.Duration // initial reconnection delay; if zero, DefaultRetry
+	MaxRetry time.Duration // longest reconnection delay; if zero, DefaultMaxRetry
+
+	serverRetry time.Duration // delay set by the server, if any
+}
+
+// A stopError ends Stream without reconnecting.
+type stopError struct{ err error }
+
+func (e *stopError) Error() string { return e.err.Error() }
+
+// Stream connects to c.URL and calls fn for each event received,
+// reconnecting as needed, until ctx is done, fn returns an error, or
+// the server tells the client to stop. It returns the context's error,
+// fn's error, nil if the server replied with 204 (No Content), or an
+// error describing an unexpected response.
+//
+// Stream is not safe for concurrent use on the same Client.
+func (c *Client) Stream(ctx context.Context, fn func(Event) error) error {
+	failures := 0
+	for {
+		connected, err := c.stream(ctx, fn)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if se, ok := err.(*stopError); ok {
+			return se.err
+		}
+		if connected {
+			failures = 0
+		}
+		t := time.NewTimer(c.delay(failures))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+		failures++
+	}
+}
+
+// delay returns the time to wait before reconnecting after the given
+// number of consecutive failed attempts.
+func (c *Client) delay(failures int) time.Duration {
+	d := c.Retry
+	if c.serverRetry > 0 {
+		d = c.serverRetry
+	} else if d <= 0 {
+		d = DefaultRetry
+	}
+	maxDelay := c.MaxRetry
+	if maxDelay <= 0 {
+		maxDelay = DefaultMaxRetry
+	}
+	for range failures {
+		if d >= maxDelay/2 {
+			return maxDelay
+		}
+		d *= 2
+	}
+	return min(d, maxDelay)
+}
+
+// stream makes one connection, reporting whether it reached the event
+// stream. A stopError means the Client should not reconnect.
+func (c *Client) stream(ctx context.Context, fn func(Event) error) (connected bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.URL, nil)
+	if err != nil {
+		return false, &stopError{err}
+	}
+	for k, vv := range c.Header {
+		req.Header[k] = append([]string(nil), vv...)
+	}
+	req.Header.Set("Accept", ContentType)
+	req.Header.Set("Cache-Control", "no-cache")
+	if c.LastEventID != "" {
+		req.Header.Set("Last-Event-ID", c.LastEventID)
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	switch {
+	case res.StatusCode == http.StatusNoContent:
+		return false, &stopError{nil}
+	case res.StatusCode >= 500:
+		return false, fmt.Errorf("sse: %s", res.Status)
+	case res.StatusCode != http.StatusOK:
+		return false, &stopError{fmt.Errorf("sse: unexpected response status %s", res.Status)}
+	}
+	if mt, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); mt != ContentType {
+		return false, &stopError{fmt.Errorf("sse: unexpected Content-Type %q", res.Header.Get("Content-Type"))}
+	}
+
+	r := NewReader(res.Body)
+	r.idBuf, r.lastID = c.LastEventID, c.LastEventID
+	for {
+		e, err := r.Next()
+		c.LastEventID = r.LastEventID()
+		if d := r.Retry(); d > 0 {
+			c.serverRetry = d
+		}
+		if err != nil {
+			if errors.Is(err, ErrLineTooLong) {
+				return true, &stopError{err}
+			}
+			return true, err
+		}
+		if err := fn(e); err != nil {
+			return true, &stopError{err}
+		}
+	}
+}
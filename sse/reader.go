@@ -0,0 +1,144 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sse
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxLineSize is the length of the longest line a [Reader] accepts.
+const MaxLineSize = 1 << 20
+
+// ErrLineTooLong is returned by [Reader.Next] for a line longer than
+// MaxLineSize.
+var ErrLineTooLong = errors.New("sse: line too long")
+
+// A Reader parses an event stream.
+type Reader struct {
+	br      *bufio.Reader
+	started bool // whether a byte order mark may no longer appear
+	skipLF  bool // whether the last line ended with CR
+
+	idBuf  string // last event ID buffer, committed at the end of each event
+	lastID string
+	retry  time.Duration
+}
+
+// NewReader returns a Reader parsing the event stream read from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{br: bufio.NewReader(r)}
+}
+
+// Next returns the next event in the stream. The event's ID is the
+// last event ID, which persists from one event to the next until the
+// stream changes it. At the end of the stream, Next returns io.EOF;
+// an incomplete event at the end of the stream is discarded.
+func (r *Reader) Next() (Event, error) {
+	var (
+		e        Event
+		data     strings.Builder
+		hasData  bool
+		hasRetry bool
+	)
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return Event{}, err
+		}
+		if line == "" {
+			// Dispatch the event.
+			r.lastID = r.idBuf
+			if !hasData {
+				e, hasRetry = Event{}, false
+				continue
+			}
+			e.ID = r.lastID
+			e.Data = strings.TrimSuffix(data.String(), "\n")
+			if !hasRetry {
+				e.Retry = 0
+			}
+			return e, nil
+		}
+		if line[0] == ':' {
+			continue // comment
+		}
+		field, value, ok := strings.Cut(line, ":")
+		if ok {
+			value = strings.TrimPrefix(value, " ")
+		}
+		switch field {
+		case "event":
+			e.Type = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				r.idBuf = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				r.retry = time.Duration(ms) * time.Millisecond
+				e.Retry = r.retry
+				hasRetry = true
+			}
+		}
+	}
+}
+
+// LastEventID returns the last event ID set by the stream.
+func (r *Reader) LastEventID() string {
+	return r.lastID
+}
+
+// Retry returns the reconnection time last set by the stream, or zero.
+func (r *Reader) Retry() time.Duration {
+	return r.retry
+}
+
+// readLine reads a line ending with CRLF, LF or CR.
+func (r *Reader) readLine() (string, error) {
+	var b []byte
+	for {
+		c, err := r.br.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if r.skipLF {
+			r.skipLF = false
+			if c == '\n' {
+				continue
+			}
+		}
+		if !r.started {
+			r.started = true
+			// Skip a byte order mark.
+			if c == 0xEF {
+				if bom, err := r.br.Peek(2); err == nil && string(bom) == "\xBB\xBF" {
+					r.br.Discard(2)
+					continue
+				}
+			}
+		}
+		switch c {
+		case '\n':
+			return string(b), nil
+		case '\r':
+			// Don't wait for a LF that may not come.
+			r.skipLF = true
+			return string(b), nil
+		}
+		if len(b) >= MaxLineSize {
+			return "", ErrLineTooLong
+		}
+		b = append(b, c)
+	}
+}
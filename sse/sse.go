@@ -0,0 +1,80 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sse implements Server-Sent Events, the text/event-stream
+// format of the HTML Living Standard.
+//
+// On the server, a [Writer] sends events on a response, flushing each
+// one to the client. On the client, a [Reader] parses an event stream
+// and a [Client] receives events from a URL, reconnecting when the
+// connection is lost and resuming from the last event ID, as a
+// browser's EventSource does.
+//
+// The format is described at
+// https://html.spec.whatwg.org/multipage/server-sent-events.html.
+package sse
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of an event stream.
+const ContentType = "text/event-stream"
+
+// An Event is a server-sent event.
+type Event struct {
+	// ID is the event's ID. A client resuming the stream sends the
+	// ID of the last event it received in the Last-Event-ID header.
+	// When sending, an empty ID leaves the last event ID unchanged.
+	ID string
+
+	// Type is the event type; empty means "message".
+	Type string
+
+	// Data is the event data. It may contain several lines.
+	// Events with empty data are not delivered to clients, although
+	// their ID and Retry still take effect.
+	Data string
+
+	// Retry, if positive, is the time a client should wait before
+	// reconnecting after losing the connection.
+	Retry time.Duration
+}
+
+// marshal appends the encoding of e to b.
+func (e *Event) marshal(b *bytes.Buffer) error {
+	if strings.ContainsAny(e.ID, "\r\n\x00") {
+		return errors.New("sse: invalid event ID " + strconv.Quote(e.ID))
+	}
+	if strings.ContainsAny(e.Type, "\r\n") {
+		return errors.New("sse: invalid event type " + strconv.Quote(e.Type))
+	}
+	if e.ID != "" {
+		b.WriteString("id: " + e.ID + "\n")
+	}
+	if e.Type != "" {
+		b.WriteString("event: " + e.Type + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	if e.Data != "" {
+		for _, line := range splitLines(e.Data) {
+			b.WriteString("data: " + line + "\n")
+		}
+	}
+	b.WriteString("\n")
+	return nil
+}
+
+// splitLines splits s at each CRLF, LF or CR.
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,356 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/johnsiilver/http/httptest"
+)
+
+func TestEventMarshal(t *testing.T) {
+	for _, tt := range []struct {
+		e    Event
+		want string
+	}{
+		{Event{Data: "hello"}, "data: hello\n\n"},
+		{Event{ID: "7", Type: "update", Data: "a\nb\r\nc"}, "id: 7\nevent: update\ndata: a\ndata: b\ndata: c\n\n"},
+		{Event{Retry: 1500 * time.Millisecond}, "retry: 1500\n\n"},
+		{Event{Data: "\n"}, "data: \ndata: \n\n"},
+	} {
+		var b bytes.Buffer
+		if err := tt.e.marshal(&b); err != nil {
+			t.Errorf("%+v: %v", tt.e, err)
+			continue
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("%+v encoded as %q; want %q", tt.e, got, tt.want)
+		}
+	}
+	for _, e := range []Event{{ID: "a\nb"}, {ID: "a\x00"}, {Type: "a\rb"}} {
+		if err := e.marshal(new(bytes.Buffer)); err == nil {
+			t.Errorf("%+v: no error", e)
+		}
+	}
+}
+
+func readAll(t *testing.T, stream string) ([]Event, *Reader) {
+	t.Helper()
+	r := NewReader(strings.NewReader(stream))
+	var events []Event
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			return events, r
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		events = append(events, e)
+	}
+}
+
+func TestReader(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		stream string
+		want   []Event
+		lastID string
+		retry  time.Duration
+	}{
+		{
+			name:   "spec example",
+			stream: "data: YHOO\ndata: +2\ndata: 10\n\n",
+			want:   []Event{{Data: "YHOO\n+2\n10"}},
+		},
+		{
+			name:   "ids and comments",
+			stream: ": test stream\n\ndata: first event\nid: 1\n\ndata:second event\nid\n\ndata:  third event\n\n",
+			want:   []Event{{ID: "1", Data: "first event"}, {Data: "second event"}, {Data: " third event"}},
+		},
+		{
+			name:   "empty data",
+			stream: "data\n\ndata\ndata\n\ndata:\n",
+			want:   []Event{{Data: ""}, {Data: "\n"}},
+		},
+		{
+			name:   "line endings",
+			stream: "\xEF\xBB\xBFevent: a\r\ndata: 1\r\n\r\nevent: b\rdata: 2\r\rdata: 3\n\n",
+			want:   []Event{{Type: "a", Data: "1"}, {Type: "b", Data: "2"}, {Data: "3"}},
+		},
+		{
+			name:   "id persists",
+			stream: "id: 5\ndata: a\n\ndata: b\n\nid: 6\n\nid: 7\ndata: incomplete",
+			want:   []Event{{ID: "5", Data: "a"}, {ID: "5", Data: "b"}},
+			lastID: "6",
+		},
+		{
+			name:   "retry",
+			stream: "retry: 250\ndata: a\n\nretry: x\nretry: -1\nretry: 1.5\ndata: b\n\n",
+			want:   []Event{{Data: "a", Retry: 250 * time.Millisecond}, {Data: "b"}},
+			retry:  250 * time.Millisecond,
+		},
+		{
+			name:   "id with NUL ignored",
+			stream: "id: 1\nid: a\x00b\ndata: x\n\n",
+			want:   []Event{{ID: "1", Data: "x"}},
+			lastID: "1",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, r := readAll(t, tt.stream)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("events:\n%+v\nwant:\n%+v", got, tt.want)
+			}
+			lastID := tt.lastID
+			if lastID == "" && len(tt.want) > 0 {
+				lastID = tt.want[len(tt.want)-1].ID
+			}
+			if r.LastEventID() != lastID {
+				t.Errorf("LastEventID = %q; want %q", r.LastEventID(), lastID)
+			}
+			if r.Retry() != tt.retry {
+				t.Errorf("Retry = %v; want %v", r.Retry(), tt.retry)
+			}
+		})
+	}
+}
+
+func TestReaderRoundTrip(t *testing.T) {
+	events := []Event{
+		{ID: "1", Type: "a", Data: "one"},
+		{ID: "2", Data: "two\nlines"},
+		{ID: "2", Type: "b", Data: " leading space"},
+	}
+	var b bytes.Buffer
+	for _, e := range events {
+		if err := e.marshal(&b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, _ := readAll(t, b.String()); !slices.Equal(got, events) {
+		t.Errorf("round trip:\n%+v\nwant:\n%+v", got, events)
+	}
+}
+
+func TestReaderLineTooLong(t *testing.T) {
+	r := NewReader(strings.NewReader("data: " + strings.Repeat("x", MaxLineSize) + "\n\n"))
+	if _, err := r.Next(); err != ErrLineTooLong {
+		t.Errorf("Next = %v; want ErrLineTooLong", err)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		w, err := NewWriter(rw, req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		w.Comment("resuming after " + LastEventID(req))
+		w.Send(Event{ID: "2", Type: "tick", Data: "a\nb"})
+		w.Send(Event{Retry: time.Second})
+	}))
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	req.Header.Set("Last-Event-ID", "1")
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q; want %q", ct, ContentType)
+	}
+	if cc := res.Header.Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("Cache-Control = %q; want no-cache", cc)
+	}
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ":resuming after 1\n\nid: 2\nevent: tick\ndata: a\ndata: b\n\nretry: 1000\n\n"
+	if string(b) != want {
+		t.Errorf("body:\n%q\nwant:\n%q", b, want)
+	}
+}
+
+func TestWriterFlushesAndDetectsDisconnect(t *testing.T) {
+	sent := make(chan bool)
+	done := make(chan error, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		w, err := NewWriter(rw, req)
+		if err != nil {
+			done <- err
+			return
+		}
+		defer w.Heartbeat(10 * time.Millisecond)()
+		w.Send(Event{Data: "first"})
+		<-sent
+		<-w.Done()
+		done <- w.Send(Event{Data: "after disconnect"})
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(res.Body)
+	// The event arrives while the handler is still running.
+	if line, err := br.ReadString('\n'); line != "data: first\n" || err != nil {
+		t.Fatalf("first line = %q, %v", line, err)
+	}
+	close(sent)
+	// Heartbeats follow.
+	br.ReadString('\n')
+	if line, err := br.ReadString('\n'); line != ":\n" || err != nil {
+		t.Errorf("heartbeat = %q, %v; want %q", line, err, ":\n")
+	}
+	cancel()
+	res.Body.Close()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("Send after disconnect succeeded")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("handler did not notice the disconnect")
+	}
+}
+
+func TestClient(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+		lastIDs  []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		lastIDs = append(lastIDs, LastEventID(req))
+		mu.Unlock()
+		switch n {
+		case 1:
+			w, _ := NewWriter(rw, req)
+			w.Send(Event{ID: "1", Data: "one", Retry: 10 * time.Millisecond})
+			// End the stream; the client reconnects.
+		case 2:
+			// A transient failure; the client reconnects.
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		case 3:
+			w, _ := NewWriter(rw, req)
+			w.Send(Event{ID: "2", Type: "two", Data: "two"})
+		default:
+			// Tell the client to stop.
+			rw.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	c := &Client{Client: ts.Client(), URL: ts.URL, Retry: time.Hour}
+	var got []Event
+	err := c.Stream(context.Background(), func(e Event) error {
+		got = append(got, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	want := []Event{
+		{ID: "1", Data: "one", Retry: 10 * time.Millisecond},
+		{ID: "2", Type: "two", Data: "two"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("events:\n%+v\nwant:\n%+v", got, want)
+	}
+	if want := []string{"", "1", "1", "2"}; !slices.Equal(lastIDs, want) {
+		t.Errorf("Last-Event-ID headers = %q; want %q", lastIDs, want)
+	}
+	if c.LastEventID != "2" {
+		t.Errorf("LastEventID = %q; want 2", c.LastEventID)
+	}
+}
+
+func TestClientStops(t *testing.T) {
+	errStop := errors.New("stop")
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/missing":
+			http.NotFound(rw, req)
+		case "/html":
+			io.WriteString(rw, "<html></html>")
+		default:
+			w, _ := NewWriter(rw, req)
+			w.Send(Event{Data: "x"})
+			<-req.Context().Done()
+		}
+	}))
+	defer ts.Close()
+
+	for _, tt := range []struct {
+		path string
+		fn   func(Event) error
+		want func(error) bool
+	}{
+		{"/missing", nil, func(err error) bool { return err != nil && strings.Contains(err.Error(), "404") }},
+		{"/html", nil, func(err error) bool { return err != nil && strings.Contains(err.Error(), "Content-Type") }},
+		{"/events", func(Event) error { return errStop }, func(err error) bool { return err == errStop }},
+	} {
+		c := &Client{Client: ts.Client(), URL: ts.URL + tt.path}
+		fn := tt.fn
+		if fn == nil {
+			fn = func(Event) error { return nil }
+		}
+		if err := c.Stream(context.Background(), fn); !tt.want(err) {
+			t.Errorf("%s: Stream = %v", tt.path, err)
+		}
+	}
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		c := &Client{Client: ts.Client(), URL: ts.URL + "/events"}
+		err := c.Stream(ctx, func(Event) error {
+			cancel()
+			return nil
+		})
+		if err != context.Canceled {
+			t.Errorf("Stream = %v; want context.Canceled", err)
+		}
+	})
+}
+
+func TestClientDelay(t *testing.T) {
+	c := &Client{Retry: time.Second, MaxRetry: 5 * time.Second}
+	var got []time.Duration
+	for i := range 5 {
+		got = append(got, c.delay(i))
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	if !slices.Equal(got, want) {
+		t.Errorf("delays = %v; want %v", got, want)
+	}
+	c.serverRetry = 100 * time.Millisecond
+	if d := c.delay(0); d != 100*time.Millisecond {
+		t.Errorf("with server retry, delay = %v; want 100ms", d)
+	}
+	if d := (&Client{}).delay(0); d != DefaultRetry {
+		t.Errorf("default delay = %v; want %v", d, DefaultRetry)
+	}
+}
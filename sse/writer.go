@@ -0,0 +1,141 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sse
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A Writer sends events on the response to a request.
+//
+// Each event is flushed to the client as soon as it is sent. Once the
+// request's context is done, as when the client disconnects, or a
+// write fails, every method returns the error.
+//
+// A Writer's methods may be called from multiple goroutines, but not
+// after the handler has returned.
+type Writer struct {
+	rw  http.ResponseWriter
+	rc  *http.ResponseController
+	ctx context.Context
+
+	mu  sync.Mutex
+	err error // sticky write error
+}
+
+// NewWriter prepares rw to send events in reply to req. It sets the
+// Content-Type and Cache-Control headers, unless they are already
+// set, and sends the response header. It returns an error if rw
+// cannot be flushed.
+func NewWriter(rw http.ResponseWriter, req *http.Request) (*Writer, error) {
+	h := rw.Header()
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", ContentType)
+	}
+	if h.Get("Cache-Control") == "" {
+		h.Set("Cache-Control", "no-cache")
+	}
+	// Ask proxies such as nginx not to buffer the stream.
+	h.Set("X-Accel-Buffering", "no")
+	h.Del("Content-Length")
+	w := &Writer{
+		rw:  rw,
+		rc:  http.NewResponseController(rw),
+		ctx: req.Context(),
+	}
+	rw.WriteHeader(http.StatusOK)
+	if err := w.rc.Flush(); err != nil {
+		return nil, fmt.Errorf("sse: %w", err)
+	}
+	return w, nil
+}
+
+// LastEventID returns the ID of the last event received by a client
+// resuming an event stream, or "" if it is not resuming.
+func LastEventID(req *http.Request) string {
+	return req.Header.Get("Last-Event-ID")
+}
+
+// Done returns a channel that is closed when the client disconnects.
+func (w *Writer) Done() <-chan struct{} {
+	return w.ctx.Done()
+}
+
+// Send sends e to the client.
+func (w *Writer) Send(e Event) error {
+	var b bytes.Buffer
+	if err := e.marshal(&b); err != nil {
+		return err
+	}
+	return w.write(b.Bytes())
+}
+
+// Comment sends a comment, which clients ignore. A comment keeps an
+// idle connection from being closed by proxies.
+func (w *Writer) Comment(text string) error {
+	var b bytes.Buffer
+	for _, line := range splitLines(text) {
+		b.WriteString(":" + line + "\n")
+	}
+	b.WriteString("\n")
+	return w.write(b.Bytes())
+}
+
+// Heartbeat sends an empty comment every interval until stop is
+// called, the client disconnects or a write fails. Stop waits for
+// the heartbeats to end, and must be called before the handler
+// returns.
+func (w *Writer) Heartbeat(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				if w.write([]byte(":\n\n")) != nil {
+					return
+				}
+			case <-done:
+				return
+			case <-w.ctx.Done():
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}
+}
+
+func (w *Writer) write(p []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	if w.ctx.Err() != nil {
+		w.err = context.Cause(w.ctx)
+		return w.err
+	}
+	if _, err := w.rw.Write(p); err != nil {
+		w.err = err
+		return err
+	}
+	if err := w.rc.Flush(); err != nil {
+		w.err = err
+		return err
+	}
+	return nil
+}